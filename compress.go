@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/klauspost/compress/zstd"
+)
+
+// Message compressions
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// Default for -max-decompressed-size
+const defaultMaxDecompressedSize = 16 << 20
+
+// A message whose compression is given by its prefix starts with encodingMagic
+// followed by one of these, in the same way as an encoding prefix.
+var compressionsByPrefix = map[byte]string{
+	'g': compressionGzip,
+	'z': compressionZstd,
+}
+
+// Magic numbers at the start of compressed data. A category with compression
+// set passes through messages that don't start with it so producers can switch
+// over gradually.
+var compressionMagic = map[string][]byte{
+	compressionGzip: {0x1f, 0x8b},
+	compressionZstd: {0x28, 0xb5, 0x2f, 0xfd},
+}
+
+// errTooLarge is returned when a message decompresses to more than the limit
+type errTooLarge struct {
+	limit int64
+}
+
+func (e *errTooLarge) Error() string {
+	return fmt.Sprintf("decompressed message is larger than %d bytes", e.limit)
+}
+
+func validateCompression(compression string) error {
+	if _, ok := compressionMagic[compression]; compression != "" && !ok {
+		return fmt.Errorf("unknown compression %q", compression)
+	}
+	return nil
+}
+
+// decompressor decompresses messages up to a size limit so that a small
+// compressed message can't exhaust memory.
+type decompressor struct {
+	limit int64
+	zstd  *zstd.Decoder
+}
+
+func newDecompressor(limit int64) (*decompressor, error) {
+	// The decoder is only used with DecodeAll which is safe for concurrent use.
+	// Max memory stops decoding within a block of passing the limit. It also
+	// rejects frames with a window larger than the limit, which zstd's defaults
+	// keep to 8MB.
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	return &decompressor{limit: limit, zstd: dec}, nil
+}
+
+// decompress returns raw decompressed if it has a compression prefix, or if
+// compression is set and it starts with that compression's magic number.
+// Otherwise raw is returned as is and compressed is false.
+func (d *decompressor) decompress(raw []byte, compression string) (out []byte, compressed bool, err error) {
+	if len(raw) > 1 && raw[0] == encodingMagic {
+		if prefixed, ok := compressionsByPrefix[raw[1]]; ok {
+			compression = prefixed
+			raw = raw[2:]
+		} else {
+			// Either an encoding prefix or invalid, which decoding will report
+			return raw, false, nil
+		}
+	} else if compression == "" || !bytes.HasPrefix(raw, compressionMagic[compression]) {
+		return raw, false, nil
+	}
+
+	if d == nil {
+		return nil, true, errors.New("decompression is not enabled")
+	}
+	switch compression {
+	case compressionGzip:
+		out, err = d.gunzip(raw)
+	case compressionZstd:
+		out, err = d.zstd.DecodeAll(raw, nil)
+		if err == zstd.ErrDecoderSizeExceeded || err == zstd.ErrWindowSizeExceeded {
+			err = &errTooLarge{d.limit}
+		}
+	}
+	if err == nil && int64(len(out)) > d.limit {
+		err = &errTooLarge{d.limit}
+	}
+	return out, true, err
+}
+
+func (d *decompressor) gunzip(raw []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	// Read one byte past the limit to tell if it was reached
+	out, err := ioutil.ReadAll(io.LimitReader(r, d.limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > d.limit {
+		return nil, &errTooLarge{d.limit}
+	}
+	return out, r.Close()
+}
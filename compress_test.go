@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/klauspost/compress/zstd"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, b []byte) []byte {
+	// The smallest window so it fits in the test's limit
+	enc, err := zstd.NewWriter(nil, zstd.WithWindowSize(zstd.MinWindowSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(b, nil)
+}
+
+func TestDecompress(t *testing.T) {
+	type testCase struct {
+		name             string
+		input            []byte
+		compression      string
+		expectOut        []byte
+		expectCompressed bool
+		expectErr        bool
+		expectTooLarge   bool
+	}
+
+	msg := []byte("{\"channels\":[\"foo\"], \"data\":{\"text\":\"" + string(bytes.Repeat([]byte("a"), 500)) + "\"}}")
+	bomb := bytes.Repeat([]byte(" "), 2000)
+
+	tests := []testCase{
+		{
+			name:      "Uncompressed",
+			input:     msg,
+			expectOut: msg,
+		},
+		{
+			name:        "Uncompressed in compressed category",
+			input:       msg,
+			compression: compressionZstd,
+			expectOut:   msg,
+		},
+		{
+			name:             "Gzip by category",
+			input:            gzipBytes(t, msg),
+			compression:      compressionGzip,
+			expectOut:        msg,
+			expectCompressed: true,
+		},
+		{
+			name:             "Zstd by category",
+			input:            zstdBytes(t, msg),
+			compression:      compressionZstd,
+			expectOut:        msg,
+			expectCompressed: true,
+		},
+		{
+			name:             "Gzip by prefix",
+			input:            append([]byte{encodingMagic, 'g'}, gzipBytes(t, msg)...),
+			expectOut:        msg,
+			expectCompressed: true,
+		},
+		{
+			name:             "Zstd by prefix overrides category",
+			input:            append([]byte{encodingMagic, 'z'}, zstdBytes(t, msg)...),
+			compression:      compressionGzip,
+			expectOut:        msg,
+			expectCompressed: true,
+		},
+		{
+			name:      "Encoding prefix is left alone",
+			input:     []byte{encodingMagic, 'm', 0x80},
+			expectOut: []byte{encodingMagic, 'm', 0x80},
+		},
+		{
+			name:             "Corrupt gzip",
+			input:            append([]byte{encodingMagic, 'g'}, msg...),
+			expectCompressed: true,
+			expectErr:        true,
+		},
+		{
+			name:             "Gzip bomb",
+			input:            gzipBytes(t, bomb),
+			compression:      compressionGzip,
+			expectCompressed: true,
+			expectErr:        true,
+			expectTooLarge:   true,
+		},
+		{
+			name:             "Zstd bomb",
+			input:            zstdBytes(t, bomb),
+			compression:      compressionZstd,
+			expectCompressed: true,
+			expectErr:        true,
+			expectTooLarge:   true,
+		},
+	}
+
+	d, err := newDecompressor(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		out, compressed, err := d.decompress(test.input, test.compression)
+		if compressed != test.expectCompressed {
+			t.Errorf("Failed case %s: expected compressed %v got %v", test.name, test.expectCompressed, compressed)
+		}
+		if (err != nil) != test.expectErr {
+			t.Errorf("Failed case %s: expected error %v got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if _, tooLarge := err.(*errTooLarge); tooLarge != test.expectTooLarge {
+			t.Errorf("Failed case %s: expected too large %v got %v", test.name, test.expectTooLarge, err)
+		}
+		if err == nil && !bytes.Equal(out, test.expectOut) {
+			t.Errorf("Failed case %s: expected %q got %q", test.name, test.expectOut, out)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+)
+
+// Config is the optional JSON file given with -config. It holds processing rules
+// that can be changed without a restart by editing the file and sending SIGHUP.
+type Config struct {
+	// Categories holds rules applied to every message received in a Scribe category
+	Categories map[string]*routeConfig `json:"categories"`
+	// Namespaces holds rules applied to messages by centrifugo channel namespace.
+	// Channels with no namespace use the "" key.
+	Namespaces map[string]*routeConfig `json:"namespaces"`
+	// Filters decide which messages are published at all
+	Filters *filterConfig `json:"filters"`
+	// Limits are the size limits for every message. Namespace limits override them.
+	Limits *sizeLimitConfig `json:"limits"`
+	// Clients maps TLS client certificate subjects to the categories they may log
+	// to. If it is empty any client may log to any category.
+	Clients map[string][]string `json:"clients"`
+	// Listeners are extra Thrift listeners to serve alongside -addr
+	Listeners []*listenerConfig `json:"listeners"`
+
+	// defaultRoute is the rules for categories with none of their own, which
+	// are set by the listener a message was received on
+	defaultRoute *routeConfig
+}
+
+// routeConfig is the set of rules for one category or namespace
+type routeConfig struct {
+	// Encoding is how messages are encoded: json (the default), msgpack or
+	// protobuf. It only applies to categories.
+	Encoding string `json:"encoding"`
+	// Compression is how messages are compressed: gzip, zstd or "" for none.
+	// It only applies to categories.
+	Compression string           `json:"compression"`
+	Transforms  []*transformRule `json:"transforms"`
+	// Enrich adds debug fields to delivered payloads. A namespace setting overrides
+	// the category one so `"enrich": {}` turns it off for a namespace.
+	Enrich *enrichConfig `json:"enrich"`
+	// RateLimit limits publishing per channel and per namespace. It only applies
+	// to namespaces.
+	RateLimit *rateLimitConfig `json:"rate_limit"`
+	// Debounce publishes only the latest message per channel in each window.
+	// It only applies to namespaces.
+	Debounce *debounceConfig `json:"debounce"`
+	// Sample publishes only a fraction of messages. It only applies to namespaces.
+	Sample *sampleConfig `json:"sample"`
+	// Limits overrides the global size limits. It only applies to namespaces.
+	Limits *sizeLimitConfig `json:"limits"`
+	// RequireSignature drops messages that aren't signed with a keyring key
+	RequireSignature bool `json:"require_signature"`
+}
+
+// LoadConfig reads and validates the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("Failed to parse config %s: %s", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if err := c.Filters.validate(); err != nil {
+		return err
+	}
+	if err := c.Limits.validate(); err != nil {
+		return fmt.Errorf("limits: %s", err)
+	}
+	for name, route := range c.Categories {
+		if err := route.validate(); err != nil {
+			return fmt.Errorf("category %q: %s", name, err)
+		}
+	}
+	for name, route := range c.Namespaces {
+		if err := route.validate(); err != nil {
+			return fmt.Errorf("namespace %q: %s", name, err)
+		}
+	}
+	names := make(map[string]bool)
+	for i, l := range c.Listeners {
+		if err := l.validate(); err != nil {
+			return fmt.Errorf("listener %d: %s", i, err)
+		}
+		if names[l.Name] {
+			return fmt.Errorf("listener %d: name %q is used more than once", i, l.Name)
+		}
+		names[l.Name] = true
+	}
+	return nil
+}
+
+func (r *routeConfig) validate() error {
+	if r == nil {
+		return nil
+	}
+	if err := validateEncoding(r.Encoding); err != nil {
+		return err
+	}
+	if err := validateCompression(r.Compression); err != nil {
+		return err
+	}
+	for i, t := range r.Transforms {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("transform %d: %s", i, err)
+		}
+	}
+	if err := r.RateLimit.validate(); err != nil {
+		return err
+	}
+	if err := r.Debounce.validate(); err != nil {
+		return err
+	}
+	if err := r.Limits.validate(); err != nil {
+		return err
+	}
+	return r.Sample.validate()
+}
+
+// category returns the rules for a Scribe category, or the listener's default
+// route if it has none of its own. It is safe to call on a nil Config.
+func (c *Config) category(name string) *routeConfig {
+	if c == nil {
+		return nil
+	}
+	if route, ok := c.Categories[name]; ok {
+		return route
+	}
+	return c.defaultRoute
+}
+
+// namespace returns the rules for a channel namespace, or nil if there are none.
+// It is safe to call on a nil Config.
+func (c *Config) namespace(name string) *routeConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Namespaces[name]
+}
+
+// filters returns the message filters, which may be nil.
+// It is safe to call on a nil Config.
+func (c *Config) filters() *filterConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Filters
+}
+
+// hasNamespaceRules reports whether messages need to be split by namespace
+// before processing.
+func (c *Config) hasNamespaceRules() bool {
+	return c != nil && len(c.Namespaces) > 0
+}
+
+// hasClientRules reports whether categories are restricted by client certificate
+func (c *Config) hasClientRules() bool {
+	return c != nil && len(c.Clients) > 0
+}
+
+// clientAllowed reports whether a client whose certificate has any of subjects
+// may log to category
+func (c *Config) clientAllowed(subjects []string, category string) bool {
+	for _, subject := range subjects {
+		for _, allowed := range c.Clients[subject] {
+			if allowed == category || allowed == allCategories {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodingFor returns the message encoding for a category, or "" for the default
+func (c *Config) encodingFor(category string) string {
+	if route := c.category(category); route != nil {
+		return route.Encoding
+	}
+	return ""
+}
+
+// compressionFor returns the message compression for a category, or "" for none
+func (c *Config) compressionFor(category string) string {
+	if route := c.category(category); route != nil {
+		return route.Compression
+	}
+	return ""
+}
+
+// signatureRequired reports whether a message in category published to channels
+// must be signed, because the category or any of the channels' namespaces require it
+func (c *Config) signatureRequired(category string, channels []string) bool {
+	if route := c.category(category); route != nil && route.RequireSignature {
+		return true
+	}
+	if c == nil || len(c.Namespaces) < 1 {
+		return false
+	}
+	for _, ch := range channels {
+		if route := c.namespace(channelNamespace(ch)); route != nil && route.RequireSignature {
+			return true
+		}
+	}
+	return false
+}
+
+// transformsFor returns the transform rules for a message in category whose
+// channels are all in namespace. Category rules run before namespace rules.
+func (c *Config) transformsFor(category, namespace string) []*transformRule {
+	var rules []*transformRule
+	if route := c.category(category); route != nil {
+		rules = append(rules, route.Transforms...)
+	}
+	if route := c.namespace(namespace); route != nil {
+		rules = append(rules, route.Transforms...)
+	}
+	return rules
+}
+
+// enrichFor returns the enrichment settings for a message in category whose channels
+// are all in namespace, or nil if payloads should not be enriched.
+func (c *Config) enrichFor(category, namespace string) *enrichConfig {
+	if route := c.namespace(namespace); route != nil && route.Enrich != nil {
+		return route.Enrich
+	}
+	if route := c.category(category); route != nil {
+		return route.Enrich
+	}
+	return nil
+}
+
+// rateLimitFor returns the rate limits for a namespace, or nil if it is unlimited
+func (c *Config) rateLimitFor(namespace string) *rateLimitConfig {
+	if route := c.namespace(namespace); route != nil {
+		return route.RateLimit
+	}
+	return nil
+}
+
+// debounceFor returns the debounce settings for a namespace, or nil if messages
+// should be published immediately
+func (c *Config) debounceFor(namespace string) *debounceConfig {
+	if route := c.namespace(namespace); route != nil {
+		return route.Debounce
+	}
+	return nil
+}
+
+// sampleFor returns the sampling settings for a namespace, or nil if every
+// message should be published
+func (c *Config) sampleFor(namespace string) *sampleConfig {
+	if route := c.namespace(namespace); route != nil {
+		return route.Sample
+	}
+	return nil
+}
+
+// globalLimits returns the size limits for every message, or nil if there are none.
+// It is safe to call on a nil Config.
+func (c *Config) globalLimits() *sizeLimitConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Limits
+}
+
+// namespaceLimits returns the size limits set for a namespace itself, or nil
+func (c *Config) namespaceLimits(namespace string) *sizeLimitConfig {
+	if route := c.namespace(namespace); route != nil {
+		return route.Limits
+	}
+	return nil
+}
+
+// limitsFor returns the size limits for a namespace, which are the global limits
+// overridden by any set for the namespace, or nil if it is unlimited
+func (c *Config) limitsFor(namespace string) *sizeLimitConfig {
+	return c.globalLimits().merge(c.namespaceLimits(namespace))
+}
+
+// splitByNamespace splits messages into one message per channel namespace so
+// that per-namespace rules can be applied to each independently. If there are no
+// namespace rules, or all of a message's channels share a namespace, it is kept as is.
+func (c *Config) splitByNamespace(msgs []*centrifugoBroadcastParams) []*centrifugoBroadcastParams {
+	if !c.hasNamespaceRules() {
+		return msgs
+	}
+
+	parts := make([]*centrifugoBroadcastParams, 0, len(msgs))
+	for _, msg := range msgs {
+		var order []string
+		groups := make(map[string][]string)
+		for _, ch := range msg.Channels {
+			ns := channelNamespace(ch)
+			if _, ok := groups[ns]; !ok {
+				order = append(order, ns)
+			}
+			groups[ns] = append(groups[ns], ch)
+		}
+		if len(order) < 2 {
+			parts = append(parts, msg)
+			continue
+		}
+		for _, ns := range order {
+			part := *msg
+			part.Channels = groups[ns]
+			parts = append(parts, &part)
+		}
+	}
+	return parts
+}
+
+// channelNamespace returns the centrifugo namespace of a channel, which is the
+// part before the first ':' ignoring any private channel '$' prefix.
+// Channels without a namespace return "".
+func channelNamespace(channel string) string {
+	channel = strings.TrimPrefix(channel, "$")
+	if i := strings.Index(channel, ":"); i > 0 {
+		return channel[:i]
+	}
+	return ""
+}
+
+// watchConfig reloads the config file into the handler each time the process
+// receives SIGHUP. If the new file is invalid the old config is kept.
+func watchConfig(path string, h *Handler) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				glog.Errorf("Failed to reload config, keeping previous one. err: %s", err)
+				continue
+			}
+			h.SetConfig(cfg)
+			glog.Infof("Reloaded config from %s", path)
+		}
+	}()
+}
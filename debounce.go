@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// Debounce orderings
+const (
+	debounceByTs      = "ts"
+	debounceByArrival = "arrival"
+)
+
+// How often the debouncer checks for windows that have ended
+const debounceTick = 10 * time.Millisecond
+
+// debounceConfig makes a namespace publish only the latest message for each
+// channel per window. The first message for a channel opens a window of Window
+// milliseconds, and when it ends only the newest message received in it is published.
+// Newest is decided by the hub format `ts` field when Order is "ts" (the default),
+// falling back to arrival order for equal or missing timestamps, or purely by
+// arrival when Order is "arrival".
+type debounceConfig struct {
+	Window int    `json:"window_ms"`
+	Order  string `json:"order"`
+}
+
+func (c *debounceConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Window < 1 {
+		return errors.New("debounce window_ms must be positive")
+	}
+	switch c.Order {
+	case "", debounceByTs, debounceByArrival:
+	default:
+		return errors.New("debounce order must be ts or arrival")
+	}
+	return nil
+}
+
+// debouncer buffers the latest message for each debounced channel until its
+// window ends and then hands them to publish with the queue they were bound for.
+type debouncer struct {
+	mu      sync.Mutex
+	pending map[string]*debouncedMessage
+	publish func(queue string, cmds []centrifugoApiCommand)
+	sd      statsd.Statsd
+}
+
+type debouncedMessage struct {
+	params centrifugoBroadcastParams
+	ts     uint32
+	due    time.Time
+	// queue is the centrifugo API queue the message was processed for, which
+	// its enriched shard field names
+	queue string
+}
+
+func newDebouncer(publish func(queue string, cmds []centrifugoApiCommand), sd statsd.Statsd) *debouncer {
+	return &debouncer{
+		pending: make(map[string]*debouncedMessage),
+		publish: publish,
+		sd:      sd,
+	}
+}
+
+// add buffers a message bound for queue for each of its channels, replacing any
+// older message already waiting for that channel. It is safe to call on a nil debouncer or
+// with a nil config, in which case it returns false and the caller should publish
+// the message itself.
+func (d *debouncer) add(msg *centrifugoBroadcastParams, cfg *debounceConfig, queue string, now time.Time) bool {
+	if d == nil || cfg == nil {
+		return false
+	}
+
+	var ts uint32
+	if cfg.Order != debounceByArrival {
+		var meta hubMessageMeta
+		if err := json.Unmarshal(msg.Data, &meta); err == nil {
+			ts = meta.Ts
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ch := range msg.Channels {
+		if existing, ok := d.pending[ch]; ok {
+			if ts > 0 && ts < existing.ts {
+				// Arrived late but an even newer message is already waiting.
+				// Without a ts this one is newer by arrival order.
+				d.sd.Incr("debounced", 1)
+				continue
+			}
+			existing.params = centrifugoBroadcastParams{Channels: []string{ch}, Data: msg.Data}
+			existing.ts = ts
+			existing.queue = queue
+			d.sd.Incr("debounced", 1)
+			continue
+		}
+		d.pending[ch] = &debouncedMessage{
+			params: centrifugoBroadcastParams{Channels: []string{ch}, Data: msg.Data},
+			ts:     ts,
+			due:    now.Add(time.Duration(cfg.Window) * time.Millisecond),
+			queue:  queue,
+		}
+	}
+	return true
+}
+
+// flush publishes every message whose window has ended by now
+func (d *debouncer) flush(now time.Time) {
+	d.mu.Lock()
+	due := make(map[string][]centrifugoApiCommand)
+	for ch, m := range d.pending {
+		if now.Before(m.due) {
+			continue
+		}
+		due[m.queue] = append(due[m.queue], centrifugoApiCommand{
+			Method: "broadcast",
+			Params: m.params,
+		})
+		delete(d.pending, ch)
+	}
+	d.mu.Unlock()
+
+	for queue, cmds := range due {
+		d.publish(queue, cmds)
+	}
+}
+
+// flushAll publishes every buffered message without waiting for its window to end
+func (d *debouncer) flushAll() {
+	// Far enough ahead that every window has ended
+	d.flush(time.Unix(1<<40, 0))
+}
+
+// run flushes ended windows in the background
+func (d *debouncer) run() {
+	go func() {
+		for now := range time.Tick(debounceTick) {
+			d.flush(now)
+		}
+	}()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+func TestDebounce(t *testing.T) {
+	type testCase struct {
+		name      string
+		config    *debounceConfig
+		input     []*centrifugoBroadcastParams
+		expectOut []centrifugoApiCommand
+	}
+
+	tests := []testCase{
+		{
+			name:   "Latest by ts wins",
+			config: &debounceConfig{Window: 100},
+			input: []*centrifugoBroadcastParams{
+				{Channels: []string{"a", "b"}, Data: json.RawMessage("{\"ts\":10,\"data\":1}")},
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":12,\"data\":2}")},
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":11,\"data\":3}")},
+			},
+			expectOut: []centrifugoApiCommand{
+				{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":12,\"data\":2}")}},
+				{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"b"}, Data: json.RawMessage("{\"ts\":10,\"data\":1}")}},
+			},
+		},
+		{
+			name:   "Missing ts falls back to arrival",
+			config: &debounceConfig{Window: 100},
+			input: []*centrifugoBroadcastParams{
+				{Channels: []string{"a", "b"}, Data: json.RawMessage("{\"ts\":10,\"data\":1}")},
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"data\":2}")},
+				{Channels: []string{"b"}, Data: json.RawMessage("{\"data\":3}")},
+				{Channels: []string{"b"}, Data: json.RawMessage("{\"ts\":9,\"data\":4}")},
+			},
+			expectOut: []centrifugoApiCommand{
+				{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"data\":2}")}},
+				{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"b"}, Data: json.RawMessage("{\"ts\":9,\"data\":4}")}},
+			},
+		},
+		{
+			name:   "Latest by arrival wins",
+			config: &debounceConfig{Window: 100, Order: debounceByArrival},
+			input: []*centrifugoBroadcastParams{
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":12,\"data\":1}")},
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":10,\"data\":2}")},
+			},
+			expectOut: []centrifugoApiCommand{
+				{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"ts\":10,\"data\":2}")}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var published []centrifugoApiCommand
+		d := newDebouncer(func(queue string, cmds []centrifugoApiCommand) {
+			published = append(published, cmds...)
+		}, &statsd.NoopClient{})
+
+		now := time.Now()
+		for _, msg := range test.input {
+			if !d.add(msg, test.config, "q", now) {
+				t.Fatalf("Failed case %s: message not accepted", test.name)
+			}
+		}
+
+		d.flush(now.Add(50 * time.Millisecond))
+		if len(published) > 0 {
+			t.Errorf("Failed case %s: published before window ended: %v", test.name, published)
+		}
+
+		d.flush(now.Add(100 * time.Millisecond))
+		sort.Slice(published, func(i, j int) bool {
+			return published[i].Params.Channels[0] < published[j].Params.Channels[0]
+		})
+		if !reflect.DeepEqual(test.expectOut, published) {
+			t.Errorf("Failed case %s: expected %v got %v", test.name, test.expectOut, published)
+		}
+	}
+}
+
+func TestDebounceQueue(t *testing.T) {
+	published := make(map[string][]centrifugoApiCommand)
+	d := newDebouncer(func(queue string, cmds []centrifugoApiCommand) {
+		published[queue] = append(published[queue], cmds...)
+	}, &statsd.NoopClient{})
+
+	// Each message is published to the queue of the batch it arrived in, which
+	// is the shard it was enriched with
+	config := &debounceConfig{Window: 100}
+	now := time.Now()
+	d.add(&centrifugoBroadcastParams{Channels: []string{"a", "b"}, Data: json.RawMessage("{\"shard\":1}")}, config, "q1", now)
+	d.add(&centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"shard\":2}")}, config, "q2", now)
+	d.flushAll()
+
+	expect := map[string][]centrifugoApiCommand{
+		"q1": {{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"b"}, Data: json.RawMessage("{\"shard\":1}")}}},
+		"q2": {{Method: "broadcast", Params: centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"shard\":2}")}}},
+	}
+	if !reflect.DeepEqual(expect, published) {
+		t.Errorf("Expected %v got %v", expect, published)
+	}
+}
+
+func TestDebounceDisabled(t *testing.T) {
+	msg := &centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{}")}
+	var d *debouncer
+	if d.add(msg, &debounceConfig{Window: 100}, "q", time.Now()) {
+		t.Errorf("Expected nil debouncer not to accept messages")
+	}
+	d = newDebouncer(func(string, []centrifugoApiCommand) {}, &statsd.NoopClient{})
+	if d.add(msg, nil, "q", time.Now()) {
+		t.Errorf("Expected debouncer not to accept messages without config")
+	}
+}
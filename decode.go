@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Envelope encodings
+const (
+	encodingJSON     = "json"
+	encodingMsgpack  = "msgpack"
+	encodingProtobuf = "protobuf"
+)
+
+// encodingMagic starts a message whose encoding is given by the byte after it,
+// overriding the category's encoding. JSON text never starts with a NUL byte so
+// this can't be confused with a JSON message.
+const encodingMagic = 0x00
+
+var encodingsByPrefix = map[byte]string{
+	'j': encodingJSON,
+	'm': encodingMsgpack,
+	'p': encodingProtobuf,
+}
+
+// envelopeDecoder decodes every envelope in a raw Scribe message
+type envelopeDecoder func(raw []byte) []decodedMessage
+
+var envelopeDecoders = map[string]envelopeDecoder{
+	encodingJSON:     decodeJSONEnvelopes,
+	encodingMsgpack:  decodeMsgpackEnvelopes,
+	encodingProtobuf: decodeProtobufEnvelopes,
+}
+
+// decodedMessage is one envelope from a Scribe message along with the error from
+// decoding it, if any. Raw is kept for logging.
+type decodedMessage struct {
+	msg *centrifugoBroadcastParams
+	raw []byte
+	err error
+}
+
+func validateEncoding(encoding string) error {
+	if _, ok := envelopeDecoders[encoding]; encoding != "" && !ok {
+		return fmt.Errorf("unknown encoding %q", encoding)
+	}
+	return nil
+}
+
+// decodeEnvelopes decodes a raw Scribe message using the encoding given by its
+// prefix if it has one, otherwise the given default encoding, or JSON if that is empty.
+func decodeEnvelopes(raw []byte, encoding string) []decodedMessage {
+	if len(raw) > 1 && raw[0] == encodingMagic {
+		prefixed, ok := encodingsByPrefix[raw[1]]
+		if !ok {
+			return []decodedMessage{{raw: raw, err: fmt.Errorf("unknown encoding prefix %q", raw[1])}}
+		}
+		encoding = prefixed
+		raw = raw[2:]
+	}
+	if encoding == "" {
+		encoding = encodingJSON
+	}
+	return envelopeDecoders[encoding](raw)
+}
+
+func decodeJSONEnvelopes(raw []byte) []decodedMessage {
+	envelopes := splitEnvelopes(raw)
+	out := make([]decodedMessage, len(envelopes))
+	for i, e := range envelopes {
+		msg, err := parseMessage(e)
+		out[i] = decodedMessage{msg: msg, raw: e, err: err}
+	}
+	return out
+}
+
+// validJSONData checks raw data from a binary envelope can be passed through
+// to centrifugo as is. As in JSON envelopes it can be any JSON value. This is
+// much cheaper than decoding it.
+func validJSONData(data []byte) error {
+	if !json.Valid(data) {
+		return errors.New("data is not valid JSON")
+	}
+	return nil
+}
+
+// dataExpiry returns the hub format ts and ttl in data, which are zero if it
+// isn't an object with them. Binary envelopes fall back to these when they have
+// no ts or ttl of their own, so expiry works the same as in JSON envelopes.
+func dataExpiry(data []byte) (ts, ttl uint32) {
+	var meta hubMessageMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return 0, 0
+	}
+	return meta.Ts, meta.TTL
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// protoField appends a length delimited protobuf field
+func protoField(b []byte, field int, value string) []byte {
+	b = appendUvarint(b, uint64(field<<3|protoWireBytes))
+	b = appendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// protoVarint appends a varint protobuf field
+func protoVarint(b []byte, field int, value uint64) []byte {
+	b = appendUvarint(b, uint64(field<<3|protoWireVarint))
+	return appendUvarint(b, value)
+}
+
+func TestDecodeEnvelopes(t *testing.T) {
+	type testCase struct {
+		name      string
+		input     []byte
+		encoding  string
+		expectOut []*centrifugoBroadcastParams
+		expectErr []bool
+	}
+
+	now := uint64(time.Now().Unix())
+	var pb []byte
+	pb = protoField(pb, protoFieldChannels, "foo")
+	pb = protoField(pb, protoFieldChannels, "bar")
+	pb = protoField(pb, protoFieldData, "{\"count\":1}")
+	pb = protoVarint(pb, protoFieldTs, now)
+	pb = protoVarint(pb, protoFieldTTL, 60)
+	pb = protoVarint(pb, 15, 1) // unknown field is skipped
+
+	var pbStale []byte
+	pbStale = protoField(pbStale, protoFieldChannels, "foo")
+	pbStale = protoField(pbStale, protoFieldData, "{}")
+	pbStale = protoVarint(pbStale, protoFieldTs, now-3600)
+	pbStale = protoVarint(pbStale, protoFieldTTL, 60)
+
+	// Expiry is read from data when the envelope has none
+	var pbDataStale []byte
+	pbDataStale = protoField(pbDataStale, protoFieldChannels, "foo")
+	pbDataStale = protoField(pbDataStale, protoFieldData, fmt.Sprintf("{\"ts\":%d,\"ttl\":60}", now-3600))
+
+	var pbArray []byte
+	pbArray = protoField(pbArray, protoFieldChannels, "foo")
+	pbArray = protoField(pbArray, protoFieldData, "[1,2]")
+
+	var pbUsers []byte
+	pbUsers = protoField(pbUsers, protoFieldUsers, "bob")
+	pbUsers = protoField(pbUsers, protoFieldChannelTemplate, "user#{user}")
+	pbUsers = protoField(pbUsers, protoFieldData, "{}")
+
+	// {"channels": ["foo"], "data": {"count": 1, "ok": true}}
+	mp := []byte{0x82,
+		0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0x91, 0xa3, 'f', 'o', 'o',
+		0xa4, 'd', 'a', 't', 'a', 0x82, 0xa5, 'c', 'o', 'u', 'n', 't', 0x01, 0xa2, 'o', 'k', 0xc3,
+	}
+	// [{"channels": ["a"], "data": "{\"x\":-1}"}, {"channels": "a"}]
+	mpList := []byte{0x92,
+		0x82, 0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0x91, 0xa1, 'a',
+		0xa4, 'd', 'a', 't', 'a', 0xa8, '{', '"', 'x', '"', ':', '-', '1', '}',
+		0x81, 0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0xa1, 'a',
+	}
+
+	// {"channels": ["a"], "data": [1, "b"]}, {"channels": ["a"], "data": true}
+	// and {"channels": ["a"], "data": {"ts": <an hour ago>, "ttl": 60}}
+	mpData := func(data ...byte) []byte {
+		return append([]byte{0x82,
+			0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0x91, 0xa1, 'a',
+			0xa4, 'd', 'a', 't', 'a'}, data...)
+	}
+	mpArrayData := mpData(0x92, 0x01, 0xa1, 'b')
+	mpScalarData := mpData(0xc3)
+	mpDataStale := mpData(0x82, 0xa2, 't', 's', 0xce, 0, 0, 0, 0, 0xa3, 't', 't', 'l', 0x3c)
+	binary.BigEndian.PutUint32(mpDataStale[len(mpDataStale)-9:], uint32(now-3600))
+
+	tests := []testCase{
+		{
+			name:     "JSON by default",
+			input:    []byte("{\"channels\":[\"foo\"], \"data\":{}}"),
+			encoding: "",
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"foo"}, Data: json.RawMessage("{}")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:     "Protobuf by category",
+			input:    pb,
+			encoding: encodingProtobuf,
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"foo", "bar"}, Data: json.RawMessage("{\"count\":1}")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:      "Protobuf stale",
+			input:     pbStale,
+			encoding:  encodingProtobuf,
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+		{
+			name:      "Protobuf stale by data",
+			input:     pbDataStale,
+			encoding:  encodingProtobuf,
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+		{
+			name:     "Protobuf data not an object",
+			input:    pbArray,
+			encoding: encodingProtobuf,
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"foo"}, Data: json.RawMessage("[1,2]")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:     "Protobuf by prefix with users",
+			input:    append([]byte{encodingMagic, 'p'}, pbUsers...),
+			encoding: encodingJSON,
+			expectOut: []*centrifugoBroadcastParams{
+				{Data: json.RawMessage("{}"), Users: []string{"bob"}, ChannelTemplate: "user#{user}"},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:      "Protobuf truncated",
+			input:     pb[:len(pb)-5],
+			encoding:  encodingProtobuf,
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+		{
+			name:     "Msgpack by prefix",
+			input:    append([]byte{encodingMagic, 'm'}, mp...),
+			encoding: "",
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"foo"}, Data: json.RawMessage("{\"count\":1,\"ok\":true}")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:     "Msgpack array of envelopes",
+			input:    mpList,
+			encoding: encodingMsgpack,
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"a"}, Data: json.RawMessage("{\"x\":-1}")},
+				nil,
+			},
+			expectErr: []bool{false, true},
+		},
+		{
+			name:     "Msgpack array data",
+			input:    mpArrayData,
+			encoding: encodingMsgpack,
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"a"}, Data: json.RawMessage("[1,\"b\"]")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:     "Msgpack scalar data",
+			input:    mpScalarData,
+			encoding: encodingMsgpack,
+			expectOut: []*centrifugoBroadcastParams{
+				{Channels: []string{"a"}, Data: json.RawMessage("true")},
+			},
+			expectErr: []bool{false},
+		},
+		{
+			name:      "Msgpack stale by data",
+			input:     mpDataStale,
+			encoding:  encodingMsgpack,
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+		{
+			name:      "Msgpack truncated",
+			input:     mp[:10],
+			encoding:  encodingMsgpack,
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+		{
+			name:      "Unknown prefix",
+			input:     []byte{encodingMagic, 'z', '{', '}'},
+			encoding:  "",
+			expectOut: []*centrifugoBroadcastParams{nil},
+			expectErr: []bool{true},
+		},
+	}
+
+	for _, test := range tests {
+		out := decodeEnvelopes(test.input, test.encoding)
+		if len(out) != len(test.expectOut) {
+			t.Errorf("Failed case %s: expected %d messages got %d", test.name, len(test.expectOut), len(out))
+			continue
+		}
+		for i, d := range out {
+			if (d.err != nil) != test.expectErr[i] {
+				t.Errorf("Failed case %s: message %d expected error %v got %v", test.name, i, test.expectErr[i], d.err)
+				continue
+			}
+			if !reflect.DeepEqual(test.expectOut[i], d.msg) {
+				t.Errorf("Failed case %s: message %d expected %+v got %+v", test.name, i, test.expectOut[i], d.msg)
+			}
+		}
+	}
+}
+
+func TestDecodeMsgpackValues(t *testing.T) {
+	type testCase struct {
+		input  []byte
+		expect interface{}
+	}
+	tests := []testCase{
+		{[]byte{0xc0}, nil},
+		{[]byte{0xff}, int64(-1)},
+		{[]byte{0xd0, 0x80}, int64(-128)},
+		{[]byte{0xd1, 0xff, 0x00}, int64(-256)},
+		{[]byte{0xcd, 0x01, 0x00}, int64(256)},
+		{[]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(18446744073709551615)},
+		{[]byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, 1.5},
+		{[]byte{0xd9, 0x02, 'h', 'i'}, "hi"},
+		{[]byte{0xc4, 0x02, 0x01, 0x02}, []byte{1, 2}},
+		{[]byte{0xdc, 0x00, 0x02, 0xc2, 0xc3}, []interface{}{false, true}},
+	}
+	for _, test := range tests {
+		v, rest, err := decodeMsgpackValue(test.input, 0)
+		if err != nil || len(rest) != 0 {
+			t.Errorf("Failed case % x: unexpected error %v, rest % x", test.input, err, rest)
+			continue
+		}
+		if !reflect.DeepEqual(test.expect, v) {
+			t.Errorf("Failed case % x: expected %#v got %#v", test.input, test.expect, v)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/gopkg.in/redis.v3"
+)
+
+// dedupStore remembers message ids for a window so that messages delivered
+// more than once by Scribe are only published once.
+type dedupStore interface {
+	// seen records key and reports whether it had already been recorded
+	// within the window
+	seen(key string) (bool, error)
+	// forget removes keys recorded for messages that ended up not being published
+	// so they aren't treated as duplicates when Scribe retries them
+	forget(keys []string) error
+}
+
+// dedupKey identifies a message by its hub format `mid` if it has one, or else by
+// a hash of its channels and data.
+func dedupKey(msg *centrifugoBroadcastParams) string {
+	var meta struct {
+		Mid string `json:"mid"`
+	}
+	if err := json.Unmarshal(msg.Data, &meta); err == nil && meta.Mid != "" {
+		return "mid:" + meta.Mid
+	}
+
+	h := sha1.New()
+	for _, ch := range msg.Channels {
+		h.Write([]byte(ch))
+		h.Write([]byte{0})
+	}
+	h.Write(msg.Data)
+	return "hash:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// localDedupStore keeps ids in memory up to a maximum number. Since every id
+// lives for the same window, the oldest are always the first to expire so a
+// simple FIFO list is enough to evict them.
+type localDedupStore struct {
+	mu         sync.Mutex
+	window     time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+func newLocalDedupStore(window time.Duration, maxEntries int) *localDedupStore {
+	return &localDedupStore{
+		window:     window,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (s *localDedupStore) seen(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for front := s.order.Front(); front != nil; front = s.order.Front() {
+		e := front.Value.(*dedupEntry)
+		if now.Before(e.expires) && s.order.Len() < s.maxEntries {
+			break
+		}
+		s.order.Remove(front)
+		delete(s.entries, e.key)
+	}
+
+	if _, ok := s.entries[key]; ok {
+		return true, nil
+	}
+	s.entries[key] = s.order.PushBack(&dedupEntry{key: key, expires: now.Add(s.window)})
+	return false, nil
+}
+
+func (s *localDedupStore) forget(keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if e, ok := s.entries[key]; ok {
+			s.order.Remove(e)
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// redisDedupStore keeps ids in redis so that all scriber instances share them
+type redisDedupStore struct {
+	client *redis.Client
+	prefix string
+	window time.Duration
+}
+
+func (s *redisDedupStore) seen(key string) (bool, error) {
+	set, err := s.client.SetNX(s.prefix+key, 1, s.window).Result()
+	if err != nil {
+		return false, err
+	}
+	return !set, nil
+}
+
+func (s *redisDedupStore) forget(keys []string) error {
+	if len(keys) < 1 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	return s.client.Del(prefixed...).Err()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestDedupKey(t *testing.T) {
+	withMid := &centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{\"mid\":\"ZGerM7jv\",\"data\":1}")}
+	if key := dedupKey(withMid); key != "mid:ZGerM7jv" {
+		t.Errorf("Expected mid key got %s", key)
+	}
+
+	a := dedupKey(&centrifugoBroadcastParams{Channels: []string{"a", "b"}, Data: json.RawMessage("{\"x\":1}")})
+	b := dedupKey(&centrifugoBroadcastParams{Channels: []string{"ab"}, Data: json.RawMessage("{\"x\":1}")})
+	c := dedupKey(&centrifugoBroadcastParams{Channels: []string{"a", "b"}, Data: json.RawMessage("{\"x\":1}")})
+	if a == b {
+		t.Errorf("Expected different channels to hash differently")
+	}
+	if a != c {
+		t.Errorf("Expected identical messages to hash the same")
+	}
+}
+
+func TestLocalDedupStore(t *testing.T) {
+	s := newLocalDedupStore(50*time.Millisecond, 2)
+
+	if dup, _ := s.seen("a"); dup {
+		t.Errorf("Expected first sighting not to be a duplicate")
+	}
+	if dup, _ := s.seen("a"); !dup {
+		t.Errorf("Expected second sighting to be a duplicate")
+	}
+
+	s.forget([]string{"a"})
+	if dup, _ := s.seen("a"); dup {
+		t.Errorf("Expected forgotten key not to be a duplicate")
+	}
+
+	// Over capacity evicts oldest
+	s.seen("b")
+	s.seen("c")
+	if dup, _ := s.seen("a"); dup {
+		t.Errorf("Expected oldest key to be evicted when full")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if dup, _ := s.seen("c"); dup {
+		t.Errorf("Expected key to expire after window")
+	}
+}
+
+func TestDedupDropsRepeatedEntries(t *testing.T) {
+	entry := &scribe.LogEntry{
+		Category: "HUBD",
+		Message:  "{\"channels\":[\"foo\"], \"data\":{\"mid\":\"abc\", \"data\":{\"foo\": 1234}}}",
+	}
+	batch := &batchContext{dedup: newLocalDedupStore(time.Minute, 100)}
+
+	out, _, err := scribeEntriesToBroadcastCommand([]*scribe.LogEntry{entry, entry}, batch, &statsd.NoopClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 1 {
+		t.Errorf("Expected duplicate to be dropped, got %d commands", len(out.Data))
+	}
+	if len(batch.dedupKeys) != 1 || batch.dedupKeys[0] != "mid:abc" {
+		t.Errorf("Expected recorded key to be kept for the batch, got %v", batch.dedupKeys)
+	}
+}
+
+func TestEnableDedupMaxEntries(t *testing.T) {
+	for _, maxEntries := range []int{0, -1} {
+		h := &Handler{}
+		if err := h.EnableDedup(time.Minute, maxEntries, false, ""); err == nil {
+			t.Errorf("Expected error for max entries %d", maxEntries)
+		}
+		if h.dedup != nil {
+			t.Errorf("Expected dedup to stay disabled for max entries %d", maxEntries)
+		}
+	}
+	h := &Handler{}
+	if err := h.EnableDedup(time.Minute, 1, false, ""); err != nil || h.dedup == nil {
+		t.Errorf("Expected dedup to be enabled, err: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// enrichConfig names the fields injected into each delivered data object to help
+// debug delivery. Fields with an empty name are not added.
+type enrichConfig struct {
+	// ReceivedAt is set to the UNIX time in milliseconds the batch was received
+	ReceivedAt string `json:"received_at"`
+	// Host is set to the hostname of the scriber that handled the message
+	Host string `json:"host"`
+	// Category is set to the Scribe category the message arrived in
+	Category string `json:"category"`
+	// Shard is set to the index of the centrifugo API queue shard the message was pushed to
+	Shard string `json:"shard"`
+}
+
+// enrich adds the configured debug fields to a data payload. Payloads that are not
+// JSON objects are returned unchanged.
+func (e *enrichConfig) enrich(data json.RawMessage, category string, batch *batchContext) (json.RawMessage, error) {
+	if *e == (enrichConfig{}) {
+		return data, nil
+	}
+	v, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	fields := map[string]interface{}{
+		e.ReceivedAt: batch.receivedAt.UnixNano() / int64(time.Millisecond),
+		e.Host:       batch.hostname,
+		e.Category:   category,
+		e.Shard:      batch.shard,
+	}
+	for name, value := range fields {
+		if name == "" {
+			continue
+		}
+		if err := setPath(obj, name, value); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(obj)
+}
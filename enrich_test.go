@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEnrich(t *testing.T) {
+	batch := &batchContext{
+		receivedAt: time.Unix(1450286347, 250*int64(time.Millisecond)),
+		hostname:   "scriber1",
+		shard:      3,
+	}
+
+	e := &enrichConfig{
+		ReceivedAt: "_received_at",
+		Host:       "_debug.host",
+		Category:   "_category",
+	}
+	out, err := e.enrich(json.RawMessage("{\"foo\":1}"), "HUBD", batch)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expect := "{\"_category\":\"HUBD\",\"_debug\":{\"host\":\"scriber1\"},\"_received_at\":1450286347250,\"foo\":1}"
+	if string(out) != expect {
+		t.Errorf("Expected %s got %s", expect, out)
+	}
+
+	e = &enrichConfig{Shard: "_shard"}
+	out, err = e.enrich(json.RawMessage("[1,2]"), "HUBD", batch)
+	if err != nil || string(out) != "[1,2]" {
+		t.Errorf("Expected non-object payload to be unchanged, got %s, %v", out, err)
+	}
+}
+
+func TestEnrichForNamespaceOverridesCategory(t *testing.T) {
+	cfg := &Config{
+		Categories: map[string]*routeConfig{
+			"HUBD": {Enrich: &enrichConfig{Host: "_host"}},
+		},
+		Namespaces: map[string]*routeConfig{
+			"public": {Enrich: &enrichConfig{}},
+			"debug":  {},
+		},
+	}
+
+	if e := cfg.enrichFor("HUBD", "private"); e == nil || e.Host != "_host" {
+		t.Errorf("Expected category enrich settings, got %+v", e)
+	}
+	if e := cfg.enrichFor("HUBD", "debug"); e == nil || e.Host != "_host" {
+		t.Errorf("Expected category enrich settings when namespace has none, got %+v", e)
+	}
+	if e := cfg.enrichFor("HUBD", "public"); e == nil || *e != (enrichConfig{}) {
+		t.Errorf("Expected namespace to disable enrichment, got %+v", e)
+	}
+	if e := cfg.enrichFor("OTHER", ""); e != nil {
+		t.Errorf("Expected no enrichment, got %+v", e)
+	}
+	var nilCfg *Config
+	if e := nilCfg.enrichFor("HUBD", ""); e != nil {
+		t.Errorf("Expected no enrichment without config, got %+v", e)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/gen-go/fb303"
+	"github.com/DeviantArt/centrifugo-scriber/gen-go/publish"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// Name reported by fb303 getName
+const serviceName = "centrifugo-scriber"
+
+// version is reported by fb303 getVersion. Set it when building with
+// -ldflags "-X main.version=1.2.3".
+var version = "dev"
+
+// fb303Handler implements the standard fb303 methods that Scribe network stores
+// and monitoring call on downstream servers.
+type fb303Handler struct {
+	started  time.Time
+	counters *statsCounters
+	// health returns why messages can't be published, or nil if they can
+	health func() error
+
+	mu sync.Mutex
+	// reinitialize and shutdown are run by the fb303 calls of the same name.
+	// If either is nil the call is ignored. They are set once servers that
+	// may already be serving fb303 have started, so are guarded by mu.
+	reinitialize func()
+	shutdown     func()
+	stopping     bool
+	options      map[string]string
+}
+
+func newFb303Handler(counters *statsCounters, health func() error) *fb303Handler {
+	return &fb303Handler{
+		started:  time.Now(),
+		counters: counters,
+		health:   health,
+		options:  make(map[string]string),
+	}
+}
+
+// setReinitialize sets what the fb303 reinitialize call runs
+func (f *fb303Handler) setReinitialize(reinitialize func()) {
+	f.mu.Lock()
+	f.reinitialize = reinitialize
+	f.mu.Unlock()
+}
+
+// setShutdown sets what the fb303 shutdown call runs
+func (f *fb303Handler) setShutdown(shutdown func()) {
+	f.mu.Lock()
+	f.shutdown = shutdown
+	f.mu.Unlock()
+}
+
+// newScribeProcessor serves Log from handler along with the fb303 methods, as
+// if scribe.thrift extended fb303.thrift like upstream Scribe's does. fb may be
+// nil to serve Log alone. If handler also implements publish.thrift, Publish is
+// served too.
+func newScribeProcessor(handler scribe.Scribe, fb *fb303Handler) thrift.TProcessor {
+	p := scribe.NewScribeProcessor(handler)
+	if publisher, ok := handler.(publish.Publisher); ok {
+		for name, f := range publish.NewPublisherProcessor(publisher).ProcessorMap() {
+			p.AddToProcessorMap(name, f)
+		}
+	}
+	if fb != nil {
+		for name, f := range fb303.NewFacebookServiceProcessor(fb).ProcessorMap() {
+			p.AddToProcessorMap(name, f)
+		}
+	}
+	return p
+}
+
+// sighupReinitialize reloads everything SIGHUP does: the config, keyring and
+// TLS certificates
+func sighupReinitialize() {
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+}
+
+func (f *fb303Handler) status() (fb303.FbStatus, string) {
+	f.mu.Lock()
+	stopping := f.stopping
+	f.mu.Unlock()
+	if stopping {
+		return fb303.FbStatus_STOPPING, "Shutting down"
+	}
+	if err := f.health(); err != nil {
+		// Still running, but every batch gets TRY_LATER until redis is back
+		return fb303.FbStatus_WARNING, "Redis is unavailable: " + err.Error()
+	}
+	return fb303.FbStatus_ALIVE, ""
+}
+
+func (f *fb303Handler) GetName() (string, error) {
+	return serviceName, nil
+}
+
+func (f *fb303Handler) GetVersion() (string, error) {
+	return version, nil
+}
+
+func (f *fb303Handler) GetStatus() (fb303.FbStatus, error) {
+	status, _ := f.status()
+	return status, nil
+}
+
+func (f *fb303Handler) GetStatusDetails() (string, error) {
+	_, details := f.status()
+	return details, nil
+}
+
+func (f *fb303Handler) GetCounters() (map[string]int64, error) {
+	return f.counters.snapshot(), nil
+}
+
+func (f *fb303Handler) GetCounter(key string) (int64, error) {
+	return f.counters.get(key), nil
+}
+
+// Options don't change any behaviour, they are only stored as fb303 requires
+func (f *fb303Handler) SetOption(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options[key] = value
+	return nil
+}
+
+func (f *fb303Handler) GetOption(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.options[key], nil
+}
+
+func (f *fb303Handler) GetOptions() (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	options := make(map[string]string, len(f.options))
+	for k, v := range f.options {
+		options[k] = v
+	}
+	return options, nil
+}
+
+// GetCpuProfile isn't supported so returns no profile
+func (f *fb303Handler) GetCpuProfile(profileDurationInSec int32) (string, error) {
+	return "", nil
+}
+
+func (f *fb303Handler) AliveSince() (int64, error) {
+	return f.started.Unix(), nil
+}
+
+func (f *fb303Handler) Reinitialize() error {
+	f.mu.Lock()
+	reinitialize := f.reinitialize
+	f.mu.Unlock()
+	if reinitialize == nil {
+		glog.Info("Ignoring fb303 reinitialize, there is nothing to reload")
+		return nil
+	}
+	glog.Info("Reinitializing for fb303 reinitialize")
+	reinitialize()
+	return nil
+}
+
+func (f *fb303Handler) Shutdown() error {
+	f.mu.Lock()
+	shutdown := f.shutdown
+	if shutdown == nil {
+		f.mu.Unlock()
+		glog.Warning("Ignoring fb303 shutdown, run with -fb303-shutdown to allow it")
+		return nil
+	}
+	if f.stopping {
+		f.mu.Unlock()
+		return nil
+	}
+	f.stopping = true
+	f.mu.Unlock()
+
+	glog.Warning("Shutting down for fb303 shutdown")
+	// Called from the connection's goroutine so don't wait for it to be stopped
+	go shutdown()
+	return nil
+}
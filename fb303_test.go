@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	"github.com/DeviantArt/centrifugo-scriber/gen-go/fb303"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestFb303Status(t *testing.T) {
+	type testCase struct {
+		name          string
+		health        error
+		stopping      bool
+		expectStatus  fb303.FbStatus
+		expectDetails string
+	}
+
+	tests := []testCase{
+		{"alive", nil, false, fb303.FbStatus_ALIVE, ""},
+		{"redis down", errors.New("connection refused"), false, fb303.FbStatus_WARNING, "Redis is unavailable: connection refused"},
+		{"stopping", errors.New("connection refused"), true, fb303.FbStatus_STOPPING, "Shutting down"},
+	}
+
+	for _, test := range tests {
+		health := test.health
+		fb := newFb303Handler(newStatsCounters(&statsd.NoopClient{}), func() error { return health })
+		fb.stopping = test.stopping
+		status, _ := fb.GetStatus()
+		details, _ := fb.GetStatusDetails()
+		if status != test.expectStatus || details != test.expectDetails {
+			t.Errorf("Failed case %s: expected %v %q got %v %q", test.name, test.expectStatus, test.expectDetails, status, details)
+		}
+	}
+}
+
+func TestFb303Service(t *testing.T) {
+	counters := newStatsCounters(&statsd.NoopClient{})
+	counters.Incr("published", 3)
+	counters.Incr("dropped.stale", 1)
+	fb := newFb303Handler(counters, func() error { return nil })
+	reinitialized := make(chan bool, 1)
+	fb.setReinitialize(func() { reinitialized <- true })
+	stopped := make(chan bool, 1)
+	fb.setShutdown(func() { stopped <- true })
+
+	socket, err := thrift.NewTServerSocketTimeout("127.0.0.1:0", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := socket.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	handler := &countingScribe{received: make(chan string, 1)}
+	transportFactory := thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())
+	protocolFactory := thrift.NewTBinaryProtocolFactoryDefault()
+	server := thrift.NewTSimpleServer4(newScribeProcessor(handler, fb), socket, transportFactory, protocolFactory)
+	go server.Serve()
+	defer server.Stop()
+
+	conn, err := thrift.NewTSocketTimeout(socket.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	trans := transportFactory.GetTransport(conn)
+	client := fb303.NewFacebookServiceClientFactory(trans, protocolFactory)
+
+	if status, err := client.GetStatus(); err != nil || status != fb303.FbStatus_ALIVE {
+		t.Errorf("Expected ALIVE got %v, err %v", status, err)
+	}
+	if name, err := client.GetName(); err != nil || name != serviceName {
+		t.Errorf("Expected name %q got %q, err %v", serviceName, name, err)
+	}
+	if since, err := client.AliveSince(); err != nil || since != fb.started.Unix() {
+		t.Errorf("Expected alive since %d got %d, err %v", fb.started.Unix(), since, err)
+	}
+	expectCounters := map[string]int64{"published": 3, "dropped.stale": 1}
+	if got, err := client.GetCounters(); err != nil || !reflect.DeepEqual(got, expectCounters) {
+		t.Errorf("Expected counters %v got %v, err %v", expectCounters, got, err)
+	}
+	if got, err := client.GetCounter("published"); err != nil || got != 3 {
+		t.Errorf("Expected published 3 got %d, err %v", got, err)
+	}
+	if err := client.SetOption("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := client.GetOption("foo"); err != nil || got != "bar" {
+		t.Errorf("Expected option bar got %q, err %v", got, err)
+	}
+
+	// Scribe Log keeps working on the same connection
+	scribeClient := scribe.NewScribeClientFactory(trans, protocolFactory)
+	scribeClient.SeqId = client.SeqId
+	if result, err := scribeClient.Log([]*scribe.LogEntry{{Category: "test", Message: "hello"}}); err != nil || result != scribe.ResultCode_OK {
+		t.Errorf("Expected OK got %v, err %v", result, err)
+	}
+	if got := <-handler.received; got != "hello" {
+		t.Errorf("Expected hello got %q", got)
+	}
+
+	if err := client.Reinitialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	for name, ch := range map[string]chan bool{"reinitialize": reinitialized, "shutdown": stopped} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Errorf("Timed out waiting for %s", name)
+		}
+	}
+	if status, _ := fb.GetStatus(); status != fb303.FbStatus_STOPPING {
+		t.Errorf("Expected STOPPING after shutdown got %v", status)
+	}
+}
+
+func TestFb303ShutdownDisabled(t *testing.T) {
+	fb := newFb303Handler(newStatsCounters(&statsd.NoopClient{}), func() error { return nil })
+	if err := fb.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := fb.GetStatus(); status != fb303.FbStatus_ALIVE {
+		t.Errorf("Expected shutdown to be ignored got %v", status)
+	}
+}
+
+func TestFb303SetWhileServing(t *testing.T) {
+	fb := newFb303Handler(newStatsCounters(&statsd.NoopClient{}), func() error { return nil })
+	stopped := make(chan bool, 1)
+
+	// Calls can arrive before main has set what they run, as servers are started
+	// first. Run with -race to check they are synchronized.
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			fb.Reinitialize()
+		}
+		done <- true
+	}()
+	fb.setReinitialize(func() {})
+	fb.setShutdown(func() { stopped <- true })
+	<-done
+
+	if err := fb.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for shutdown")
+	}
+}
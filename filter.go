@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filterConfig holds expressions deciding which messages are published. A message
+// matching any Deny expression is dropped. If there are any Allow expressions a
+// message must match at least one of them to be published.
+//
+// Expressions compare message fields to literals:
+//
+//	category == "hub" && channels =~ "^spam:" || data.user.id == 1234
+//
+// Fields are `category`, `channels` (or `channel`) and `data` followed by a dotted
+// path into the payload. Literals are double quoted strings, numbers, true, false
+// and null. Operators are == != < <= > >= =~ (regexp match) !~ ! && || and
+// parentheses. When a field is a list, such as channels, a comparison is true if
+// it is true for any element, and != and !~ are true if no element matches.
+type filterConfig struct {
+	Allow []*filterRule `json:"allow"`
+	Deny  []*filterRule `json:"deny"`
+}
+
+// filterRule is a named expression. The name is used in the drop counter
+// `dropped.filter.<name>`.
+type filterRule struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+
+	expr filterExpr
+}
+
+// filterInput is the message an expression is evaluated against
+type filterInput struct {
+	category string
+	channels []string
+	data     interface{}
+}
+
+// filterNotAllowed is the drop reason for messages that matched no allow rule
+const filterNotAllowed = "not_allowed"
+
+var filterNameRe = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+func (f *filterConfig) validate() error {
+	if f == nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, rule := range append(append([]*filterRule{}, f.Allow...), f.Deny...) {
+		if !filterNameRe.MatchString(rule.Name) {
+			return fmt.Errorf("filter name %q must be non-empty and only contain letters, numbers, _ and -", rule.Name)
+		}
+		if rule.Name == filterNotAllowed || names[rule.Name] {
+			return fmt.Errorf("filter name %q is not unique", rule.Name)
+		}
+		names[rule.Name] = true
+
+		expr, err := parseFilterExpr(rule.Expr)
+		if err != nil {
+			return fmt.Errorf("filter %q: %s", rule.Name, err)
+		}
+		rule.expr = expr
+	}
+	return nil
+}
+
+// check returns the name of the rule that caused a message to be rejected, or
+// "" if it should be published.
+func (f *filterConfig) check(category string, msg *centrifugoBroadcastParams) (string, error) {
+	if f == nil || (len(f.Allow) < 1 && len(f.Deny) < 1) {
+		return "", nil
+	}
+	data, err := decodeJSONValue(msg.Data)
+	if err != nil {
+		return "", err
+	}
+	in := &filterInput{category: category, channels: msg.Channels, data: data}
+
+	for _, rule := range f.Deny {
+		if truthy(rule.expr.eval(in)) {
+			return rule.Name, nil
+		}
+	}
+	if len(f.Allow) < 1 {
+		return "", nil
+	}
+	for _, rule := range f.Allow {
+		if truthy(rule.expr.eval(in)) {
+			return "", nil
+		}
+	}
+	return filterNotAllowed, nil
+}
+
+// filterExpr is a node in a parsed filter expression
+type filterExpr interface {
+	eval(in *filterInput) interface{}
+}
+
+type filterLiteral struct {
+	v interface{}
+}
+
+func (e *filterLiteral) eval(in *filterInput) interface{} {
+	return e.v
+}
+
+type filterField struct {
+	root string
+	path []string
+}
+
+func (e *filterField) eval(in *filterInput) interface{} {
+	switch e.root {
+	case "category":
+		return in.category
+	case "channel", "channels":
+		list := make([]interface{}, len(in.channels))
+		for i, ch := range in.channels {
+			list[i] = ch
+		}
+		return list
+	}
+	v := in.data
+	for _, key := range e.path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+type filterNot struct {
+	e filterExpr
+}
+
+func (e *filterNot) eval(in *filterInput) interface{} {
+	return !truthy(e.e.eval(in))
+}
+
+type filterLogical struct {
+	and  bool
+	l, r filterExpr
+}
+
+func (e *filterLogical) eval(in *filterInput) interface{} {
+	l := truthy(e.l.eval(in))
+	if e.and != l {
+		// false && x, true || x
+		return l
+	}
+	return truthy(e.r.eval(in))
+}
+
+type filterCompare struct {
+	op   string
+	l, r filterExpr
+	re   *regexp.Regexp
+}
+
+func (e *filterCompare) eval(in *filterInput) interface{} {
+	l, r := e.l.eval(in), e.r.eval(in)
+	negate := e.op == "!=" || e.op == "!~"
+
+	items, isList := l.([]interface{})
+	if !isList {
+		items = []interface{}{l}
+	}
+	for _, item := range items {
+		if e.match(filterScalar(item), filterScalar(r)) {
+			return !negate
+		}
+	}
+	return negate
+}
+
+// match compares a single value, treating != and !~ as == and =~ since the
+// negation is applied by the caller.
+func (e *filterCompare) match(l, r interface{}) bool {
+	switch e.op {
+	case "==", "!=":
+		switch l.(type) {
+		case nil, bool, float64, string:
+			return l == r
+		}
+		// Objects and nested lists never compare equal to anything
+		return false
+	case "=~", "!~":
+		s, ok := l.(string)
+		return ok && e.re.MatchString(s)
+	}
+
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false
+		}
+		return compareOrdered(e.op, lv < rv, lv == rv)
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false
+		}
+		return compareOrdered(e.op, lv < rv, lv == rv)
+	}
+	return false
+}
+
+// filterScalar converts JSON numbers to float64 so they compare with number literals
+func filterScalar(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
+
+func compareOrdered(op string, less, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case json.Number:
+		return filterScalar(v) != 0.0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// filterParser is a recursive descent parser for filter expressions
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseFilterExpr(src string) (filterExpr, error) {
+	tokens, err := tokenizeFilterExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	l, err := p.parseAnd()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var r filterExpr
+		r, err = p.parseAnd()
+		l = &filterLogical{and: false, l: l, r: r}
+	}
+	return l, err
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	l, err := p.parseUnary()
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var r filterExpr
+		r, err = p.parseUnary()
+		l = &filterLogical{and: true, l: l, r: r}
+	}
+	return l, err
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.peek() == "!" {
+		p.next()
+		e, err := p.parseUnary()
+		return &filterNot{e: e}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (filterExpr, error) {
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		return l, nil
+	}
+	p.next()
+	r, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &filterCompare{op: op, l: l, r: r}
+	if op == "=~" || op == "!~" {
+		lit, _ := r.(*filterLiteral)
+		if lit == nil {
+			lit = &filterLiteral{}
+		}
+		pattern, ok := lit.v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be followed by a string", op)
+		}
+		if cmp.re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return cmp, nil
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, errors.New("unexpected end of expression")
+	case t == "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing )")
+		}
+		return e, nil
+	case t[0] == '"':
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", t)
+		}
+		return &filterLiteral{v: s}, nil
+	case t == "true" || t == "false":
+		return &filterLiteral{v: t == "true"}, nil
+	case t == "null":
+		return &filterLiteral{v: nil}, nil
+	case t[0] == '-' || unicode.IsDigit(rune(t[0])):
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+		return &filterLiteral{v: f}, nil
+	}
+
+	path := strings.Split(t, ".")
+	switch path[0] {
+	case "category", "channel", "channels":
+		if len(path) > 1 {
+			return nil, fmt.Errorf("%s has no fields", path[0])
+		}
+	case "data":
+	default:
+		return nil, fmt.Errorf("unknown field %q", t)
+	}
+	for _, key := range path {
+		if key == "" {
+			return nil, fmt.Errorf("invalid field %q", t)
+		}
+	}
+	return &filterField{root: path[0], path: path[1:]}, nil
+}
+
+// tokenizeFilterExpr splits an expression into operators, parentheses, quoted
+// strings and words (fields, numbers and keywords).
+func tokenizeFilterExpr(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, src[i:j+1])
+			i = j + 1
+		case strings.IndexByte("=!<>&|~", c) >= 0:
+			if i+1 < len(src) {
+				two := src[i : i+2]
+				switch two {
+				case "==", "!=", "<=", ">=", "=~", "!~", "&&", "||":
+					tokens = append(tokens, two)
+					i += 2
+					continue
+				}
+			}
+			if c != '!' && c != '<' && c != '>' {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			j := i
+			for j < len(src) && (src[j] == '.' || src[j] == '_' || src[j] == '-' ||
+				unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterExpressions(t *testing.T) {
+	type testCase struct {
+		expr   string
+		expect bool
+	}
+
+	msg := &centrifugoBroadcastParams{
+		Channels: []string{"public:foo", "spam:bar"},
+		Data:     json.RawMessage("{\"ts\":1450286347,\"data\":{\"kind\":\"like\",\"user\":{\"id\":1234},\"tags\":[\"a\",\"b\"]}}"),
+	}
+	data, err := decodeJSONValue(msg.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &filterInput{category: "HUBD", channels: msg.Channels, data: data}
+
+	tests := []testCase{
+		{`category == "HUBD"`, true},
+		{`category != "HUBD"`, false},
+		{`channels == "spam:bar"`, true},
+		{`channel =~ "^spam:"`, true},
+		{`channels !~ "^spam:"`, false},
+		{`channels != "other"`, true},
+		{`data.data.kind == "like"`, true},
+		{`data.data.user.id == 1234`, true},
+		{`data.data.user.id >= 1000 && data.data.user.id < 2000`, true},
+		{`data.data.user.id > 1234`, false},
+		{`data.data.tags == "b"`, true},
+		{`data.data.missing == null`, true},
+		{`data.data.missing`, false},
+		{`data.data.user`, true},
+		{`data.data.user == 1`, false},
+		{`data.ts > "abc"`, false},
+		{`!(category == "HUBD") || data.data.kind == "like"`, true},
+		{`category == "OTHER" || channels =~ "foo$" && data.data.kind == "x"`, false},
+		{`!data.data.kind`, false},
+	}
+
+	for _, test := range tests {
+		e, err := parseFilterExpr(test.expr)
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected parse error %v", test.expr, err)
+			continue
+		}
+		if got := truthy(e.eval(in)); got != test.expect {
+			t.Errorf("Failed case %s: expected %v got %v", test.expr, test.expect, got)
+		}
+	}
+}
+
+func TestFilterParseErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`category ==`,
+		`(category == "a"`,
+		`category == "a")`,
+		`user == "a"`,
+		`category.foo == "a"`,
+		`channels =~ 12`,
+		`channels =~ "("`,
+		`category = "a"`,
+		`category == "unterminated`,
+		`data..foo == 1`,
+	}
+	for _, expr := range invalid {
+		if _, err := parseFilterExpr(expr); err == nil {
+			t.Errorf("Failed case %s: expected parse error", expr)
+		}
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	f := &filterConfig{
+		Allow: []*filterRule{
+			{Name: "hub_only", Expr: `category == "HUBD"`},
+		},
+		Deny: []*filterRule{
+			{Name: "no_spam", Expr: `channels =~ "^spam:"`},
+		},
+	}
+	if err := f.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		category string
+		channels []string
+		expect   string
+	}
+	tests := []testCase{
+		{"HUBD", []string{"foo"}, ""},
+		{"HUBD", []string{"foo", "spam:foo"}, "no_spam"},
+		{"OTHER", []string{"foo"}, filterNotAllowed},
+	}
+	for _, test := range tests {
+		msg := &centrifugoBroadcastParams{Channels: test.channels, Data: json.RawMessage("{}")}
+		got, err := f.check(test.category, msg)
+		if err != nil {
+			t.Errorf("Failed case %v: unexpected error %v", test, err)
+			continue
+		}
+		if got != test.expect {
+			t.Errorf("Failed case %v: expected %q got %q", test, test.expect, got)
+		}
+	}
+
+	dup := &filterConfig{Deny: []*filterRule{{Name: "a", Expr: "true"}, {Name: "a", Expr: "false"}}}
+	if err := dup.validate(); err == nil {
+		t.Errorf("Expected duplicate filter names to be invalid")
+	}
+}
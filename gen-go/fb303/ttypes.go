@@ -0,0 +1,68 @@
+// Autogenerated by Thrift Compiler (0.9.2)
+// DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING
+
+package fb303
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+)
+
+// (needed to ensure safety because of naive import list construction.)
+var _ = thrift.ZERO
+var _ = fmt.Printf
+var _ = bytes.Equal
+
+var GoUnusedProtection__ int
+
+// Common status reporting mechanism across all services
+type FbStatus int64
+
+const (
+	FbStatus_DEAD     FbStatus = 0
+	FbStatus_STARTING FbStatus = 1
+	FbStatus_ALIVE    FbStatus = 2
+	FbStatus_STOPPING FbStatus = 3
+	FbStatus_STOPPED  FbStatus = 4
+	FbStatus_WARNING  FbStatus = 5
+)
+
+func (p FbStatus) String() string {
+	switch p {
+	case FbStatus_DEAD:
+		return "FbStatus_DEAD"
+	case FbStatus_STARTING:
+		return "FbStatus_STARTING"
+	case FbStatus_ALIVE:
+		return "FbStatus_ALIVE"
+	case FbStatus_STOPPING:
+		return "FbStatus_STOPPING"
+	case FbStatus_STOPPED:
+		return "FbStatus_STOPPED"
+	case FbStatus_WARNING:
+		return "FbStatus_WARNING"
+	}
+	return "<UNSET>"
+}
+
+func FbStatusFromString(s string) (FbStatus, error) {
+	switch s {
+	case "FbStatus_DEAD":
+		return FbStatus_DEAD, nil
+	case "FbStatus_STARTING":
+		return FbStatus_STARTING, nil
+	case "FbStatus_ALIVE":
+		return FbStatus_ALIVE, nil
+	case "FbStatus_STOPPING":
+		return FbStatus_STOPPING, nil
+	case "FbStatus_STOPPED":
+		return FbStatus_STOPPED, nil
+	case "FbStatus_WARNING":
+		return FbStatus_WARNING, nil
+	}
+	return FbStatus(0), fmt.Errorf("not a valid FbStatus string")
+}
+
+func FbStatusPtr(v FbStatus) *FbStatus { return &v }
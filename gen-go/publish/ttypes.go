@@ -0,0 +1,906 @@
+// Autogenerated by Thrift Compiler (0.9.2)
+// DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING
+
+package publish
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+)
+
+// (needed to ensure safety because of naive import list construction.)
+var _ = thrift.ZERO
+var _ = fmt.Printf
+var _ = bytes.Equal
+
+var GoUnusedProtection__ int
+
+// What happened to one PublishRequest
+type PublishStatus int64
+
+const (
+	PublishStatus_PUBLISHED    PublishStatus = 0
+	PublishStatus_DEBOUNCED    PublishStatus = 1
+	PublishStatus_INVALID      PublishStatus = 2
+	PublishStatus_STALE        PublishStatus = 3
+	PublishStatus_UNAUTHORIZED PublishStatus = 4
+	PublishStatus_DUPLICATE    PublishStatus = 5
+	PublishStatus_FILTERED     PublishStatus = 6
+	PublishStatus_TOO_LARGE    PublishStatus = 7
+	PublishStatus_RATE_LIMITED PublishStatus = 8
+)
+
+func (p PublishStatus) String() string {
+	switch p {
+	case PublishStatus_PUBLISHED:
+		return "PublishStatus_PUBLISHED"
+	case PublishStatus_DEBOUNCED:
+		return "PublishStatus_DEBOUNCED"
+	case PublishStatus_INVALID:
+		return "PublishStatus_INVALID"
+	case PublishStatus_STALE:
+		return "PublishStatus_STALE"
+	case PublishStatus_UNAUTHORIZED:
+		return "PublishStatus_UNAUTHORIZED"
+	case PublishStatus_DUPLICATE:
+		return "PublishStatus_DUPLICATE"
+	case PublishStatus_FILTERED:
+		return "PublishStatus_FILTERED"
+	case PublishStatus_TOO_LARGE:
+		return "PublishStatus_TOO_LARGE"
+	case PublishStatus_RATE_LIMITED:
+		return "PublishStatus_RATE_LIMITED"
+	}
+	return "<UNSET>"
+}
+
+func PublishStatusFromString(s string) (PublishStatus, error) {
+	switch s {
+	case "PublishStatus_PUBLISHED":
+		return PublishStatus_PUBLISHED, nil
+	case "PublishStatus_DEBOUNCED":
+		return PublishStatus_DEBOUNCED, nil
+	case "PublishStatus_INVALID":
+		return PublishStatus_INVALID, nil
+	case "PublishStatus_STALE":
+		return PublishStatus_STALE, nil
+	case "PublishStatus_UNAUTHORIZED":
+		return PublishStatus_UNAUTHORIZED, nil
+	case "PublishStatus_DUPLICATE":
+		return PublishStatus_DUPLICATE, nil
+	case "PublishStatus_FILTERED":
+		return PublishStatus_FILTERED, nil
+	case "PublishStatus_TOO_LARGE":
+		return PublishStatus_TOO_LARGE, nil
+	case "PublishStatus_RATE_LIMITED":
+		return PublishStatus_RATE_LIMITED, nil
+	}
+	return PublishStatus(0), fmt.Errorf("not a valid PublishStatus string")
+}
+
+func PublishStatusPtr(v PublishStatus) *PublishStatus { return &v }
+
+type ErrorCode int64
+
+const (
+	ErrorCode_TRY_LATER ErrorCode = 0
+)
+
+func (p ErrorCode) String() string {
+	switch p {
+	case ErrorCode_TRY_LATER:
+		return "ErrorCode_TRY_LATER"
+	}
+	return "<UNSET>"
+}
+
+func ErrorCodeFromString(s string) (ErrorCode, error) {
+	switch s {
+	case "ErrorCode_TRY_LATER":
+		return ErrorCode_TRY_LATER, nil
+	}
+	return ErrorCode(0), fmt.Errorf("not a valid ErrorCode string")
+}
+
+func ErrorCodePtr(v ErrorCode) *ErrorCode { return &v }
+
+// Optional extras for a PublishRequest, as in JSON envelopes
+type PublishOptions struct {
+	// Users to fan out to using channel_template, which must contain {user}
+	Users           []string `thrift:"users,1" json:"users"`
+	ChannelTemplate string   `thrift:"channel_template,2" json:"channel_template"`
+	// Id of the keyring key the request is signed with, and the raw HMAC-SHA256
+	// over its channels and data
+	KeyId     string `thrift:"key_id,3" json:"key_id"`
+	Signature []byte `thrift:"signature,4" json:"signature"`
+}
+
+func NewPublishOptions() *PublishOptions {
+	return &PublishOptions{}
+}
+
+func (p *PublishOptions) GetUsers() []string {
+	return p.Users
+}
+
+func (p *PublishOptions) GetChannelTemplate() string {
+	return p.ChannelTemplate
+}
+
+func (p *PublishOptions) GetKeyId() string {
+	return p.KeyId
+}
+
+func (p *PublishOptions) GetSignature() []byte {
+	return p.Signature
+}
+func (p *PublishOptions) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *PublishOptions) ReadField1(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([]string, 0, size)
+	p.Users = tSlice
+	for i := 0; i < size; i++ {
+		var _elem0 string
+		if v, err := iprot.ReadString(); err != nil {
+			return fmt.Errorf("error reading field 1: %s", err)
+		} else {
+			_elem0 = v
+		}
+		p.Users = append(p.Users, _elem0)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *PublishOptions) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.ChannelTemplate = v
+	}
+	return nil
+}
+
+func (p *PublishOptions) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 3: %s", err)
+	} else {
+		p.KeyId = v
+	}
+	return nil
+}
+
+func (p *PublishOptions) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadBinary(); err != nil {
+		return fmt.Errorf("error reading field 4: %s", err)
+	} else {
+		p.Signature = v
+	}
+	return nil
+}
+
+func (p *PublishOptions) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("PublishOptions"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *PublishOptions) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("users", thrift.LIST, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:users: %s", p, err)
+	}
+	if err := oprot.WriteListBegin(thrift.STRING, len(p.Users)); err != nil {
+		return fmt.Errorf("error writing list begin: %s", err)
+	}
+	for _, v := range p.Users {
+		if err := oprot.WriteString(string(v)); err != nil {
+			return fmt.Errorf("%T. (1) field write error: %s", p, err)
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return fmt.Errorf("error writing list end: %s", err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:users: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishOptions) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("channel_template", thrift.STRING, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:channel_template: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.ChannelTemplate)); err != nil {
+		return fmt.Errorf("%T.channel_template (2) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:channel_template: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishOptions) writeField3(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("key_id", thrift.STRING, 3); err != nil {
+		return fmt.Errorf("%T write field begin error 3:key_id: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.KeyId)); err != nil {
+		return fmt.Errorf("%T.key_id (3) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 3:key_id: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishOptions) writeField4(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("signature", thrift.STRING, 4); err != nil {
+		return fmt.Errorf("%T write field begin error 4:signature: %s", p, err)
+	}
+	if err := oprot.WriteBinary(p.Signature); err != nil {
+		return fmt.Errorf("%T.signature (4) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 4:signature: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishOptions) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("PublishOptions(%+v)", *p)
+}
+
+type PublishRequest struct {
+	// Channels to broadcast to
+	Channels []string `thrift:"channels,1" json:"channels"`
+	// JSON encoded payload delivered to clients, usually an object. It is passed
+	// through to centrifugo as is.
+	Data []byte `thrift:"data,2" json:"data"`
+	// UNIX timestamp (seconds) the event occurred and time to live in seconds.
+	// If both are set and the request has expired it is dropped. If neither is
+	// set, ts and ttl inside data are checked as in JSON envelopes.
+	Ts  int64 `thrift:"ts,3" json:"ts"`
+	Ttl int32 `thrift:"ttl,4" json:"ttl"`
+	// Centrifugo API method, only broadcast (the default if empty) is supported
+	Method  string          `thrift:"method,5" json:"method"`
+	Options *PublishOptions `thrift:"options,6" json:"options,omitempty"`
+	// Category to route the request as, for rules in the config. Defaults to
+	// "thrift".
+	Category string `thrift:"category,7" json:"category"`
+}
+
+func NewPublishRequest() *PublishRequest {
+	return &PublishRequest{}
+}
+
+func (p *PublishRequest) GetChannels() []string {
+	return p.Channels
+}
+
+func (p *PublishRequest) GetData() []byte {
+	return p.Data
+}
+
+func (p *PublishRequest) GetTs() int64 {
+	return p.Ts
+}
+
+func (p *PublishRequest) GetTtl() int32 {
+	return p.Ttl
+}
+
+func (p *PublishRequest) GetMethod() string {
+	return p.Method
+}
+
+var PublishRequest_Options_DEFAULT *PublishOptions
+
+func (p *PublishRequest) GetOptions() *PublishOptions {
+	if !p.IsSetOptions() {
+		return PublishRequest_Options_DEFAULT
+	}
+	return p.Options
+}
+
+func (p *PublishRequest) GetCategory() string {
+	return p.Category
+}
+func (p *PublishRequest) IsSetOptions() bool {
+	return p.Options != nil
+}
+
+func (p *PublishRequest) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		case 5:
+			if err := p.ReadField5(iprot); err != nil {
+				return err
+			}
+		case 6:
+			if err := p.ReadField6(iprot); err != nil {
+				return err
+			}
+		case 7:
+			if err := p.ReadField7(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField1(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([]string, 0, size)
+	p.Channels = tSlice
+	for i := 0; i < size; i++ {
+		var _elem1 string
+		if v, err := iprot.ReadString(); err != nil {
+			return fmt.Errorf("error reading field 1: %s", err)
+		} else {
+			_elem1 = v
+		}
+		p.Channels = append(p.Channels, _elem1)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadBinary(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.Data = v
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI64(); err != nil {
+		return fmt.Errorf("error reading field 3: %s", err)
+	} else {
+		p.Ts = v
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 4: %s", err)
+	} else {
+		p.Ttl = v
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField5(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 5: %s", err)
+	} else {
+		p.Method = v
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField6(iprot thrift.TProtocol) error {
+	p.Options = &PublishOptions{}
+	if err := p.Options.Read(iprot); err != nil {
+		return fmt.Errorf("%T error reading struct: %s", p.Options, err)
+	}
+	return nil
+}
+
+func (p *PublishRequest) ReadField7(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 7: %s", err)
+	} else {
+		p.Category = v
+	}
+	return nil
+}
+
+func (p *PublishRequest) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("PublishRequest"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField5(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField6(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField7(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *PublishRequest) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("channels", thrift.LIST, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:channels: %s", p, err)
+	}
+	if err := oprot.WriteListBegin(thrift.STRING, len(p.Channels)); err != nil {
+		return fmt.Errorf("error writing list begin: %s", err)
+	}
+	for _, v := range p.Channels {
+		if err := oprot.WriteString(string(v)); err != nil {
+			return fmt.Errorf("%T. (1) field write error: %s", p, err)
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return fmt.Errorf("error writing list end: %s", err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:channels: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("data", thrift.STRING, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:data: %s", p, err)
+	}
+	if err := oprot.WriteBinary(p.Data); err != nil {
+		return fmt.Errorf("%T.data (2) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:data: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField3(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("ts", thrift.I64, 3); err != nil {
+		return fmt.Errorf("%T write field begin error 3:ts: %s", p, err)
+	}
+	if err := oprot.WriteI64(int64(p.Ts)); err != nil {
+		return fmt.Errorf("%T.ts (3) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 3:ts: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField4(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("ttl", thrift.I32, 4); err != nil {
+		return fmt.Errorf("%T write field begin error 4:ttl: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Ttl)); err != nil {
+		return fmt.Errorf("%T.ttl (4) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 4:ttl: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField5(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("method", thrift.STRING, 5); err != nil {
+		return fmt.Errorf("%T write field begin error 5:method: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.Method)); err != nil {
+		return fmt.Errorf("%T.method (5) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 5:method: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField6(oprot thrift.TProtocol) (err error) {
+	if p.IsSetOptions() {
+		if err := oprot.WriteFieldBegin("options", thrift.STRUCT, 6); err != nil {
+			return fmt.Errorf("%T write field begin error 6:options: %s", p, err)
+		}
+		if err := p.Options.Write(oprot); err != nil {
+			return fmt.Errorf("%T error writing struct: %s", p.Options, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 6:options: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *PublishRequest) writeField7(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("category", thrift.STRING, 7); err != nil {
+		return fmt.Errorf("%T write field begin error 7:category: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.Category)); err != nil {
+		return fmt.Errorf("%T.category (7) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 7:category: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishRequest) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("PublishRequest(%+v)", *p)
+}
+
+// Result of one PublishRequest, at its position in Publish's results
+type RequestResult struct {
+	Status PublishStatus `thrift:"status,1" json:"status"`
+	// Why the request was dropped, as in the dropped.* metrics
+	Reason string `thrift:"reason,2" json:"reason"`
+}
+
+func NewRequestResult() *RequestResult {
+	return &RequestResult{}
+}
+
+func (p *RequestResult) GetStatus() PublishStatus {
+	return p.Status
+}
+
+func (p *RequestResult) GetReason() string {
+	return p.Reason
+}
+func (p *RequestResult) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *RequestResult) ReadField1(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 1: %s", err)
+	} else {
+		temp := PublishStatus(v)
+		p.Status = temp
+	}
+	return nil
+}
+
+func (p *RequestResult) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.Reason = v
+	}
+	return nil
+}
+
+func (p *RequestResult) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("RequestResult"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *RequestResult) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("status", thrift.I32, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:status: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Status)); err != nil {
+		return fmt.Errorf("%T.status (1) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:status: %s", p, err)
+	}
+	return err
+}
+
+func (p *RequestResult) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("reason", thrift.STRING, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:reason: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.Reason)); err != nil {
+		return fmt.Errorf("%T.reason (2) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:reason: %s", p, err)
+	}
+	return err
+}
+
+func (p *RequestResult) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("RequestResult(%+v)", *p)
+}
+
+type PublishError struct {
+	Code    ErrorCode `thrift:"code,1" json:"code"`
+	Message string    `thrift:"message,2" json:"message"`
+}
+
+func NewPublishError() *PublishError {
+	return &PublishError{}
+}
+
+func (p *PublishError) GetCode() ErrorCode {
+	return p.Code
+}
+
+func (p *PublishError) GetMessage() string {
+	return p.Message
+}
+func (p *PublishError) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *PublishError) ReadField1(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 1: %s", err)
+	} else {
+		temp := ErrorCode(v)
+		p.Code = temp
+	}
+	return nil
+}
+
+func (p *PublishError) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.Message = v
+	}
+	return nil
+}
+
+func (p *PublishError) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("PublishError"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *PublishError) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("code", thrift.I32, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:code: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Code)); err != nil {
+		return fmt.Errorf("%T.code (1) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:code: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishError) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("message", thrift.STRING, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:message: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.Message)); err != nil {
+		return fmt.Errorf("%T.message (2) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:message: %s", p, err)
+	}
+	return err
+}
+
+func (p *PublishError) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("PublishError(%+v)", *p)
+}
+
+func (p *PublishError) Error() string {
+	return p.String()
+}
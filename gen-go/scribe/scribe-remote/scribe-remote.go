@@ -22,6 +22,7 @@ func Usage() {
 	flag.PrintDefaults()
 	fmt.Fprintln(os.Stderr, "\nFunctions:")
 	fmt.Fprintln(os.Stderr, "  ResultCode Log( messages)")
+	fmt.Fprintln(os.Stderr, "  []*EntryResult LogWithResults( messages)")
 	fmt.Fprintln(os.Stderr)
 	os.Exit(0)
 }
@@ -142,6 +143,32 @@ func main() {
 		fmt.Print(client.Log(value0))
 		fmt.Print("\n")
 		break
+	case "LogWithResults":
+		if flag.NArg()-1 != 1 {
+			fmt.Fprintln(os.Stderr, "LogWithResults requires 1 args")
+			flag.Usage()
+		}
+		arg11 := flag.Arg(1)
+		mbTrans12 := thrift.NewTMemoryBufferLen(len(arg11))
+		defer mbTrans12.Close()
+		_, err13 := mbTrans12.WriteString(arg11)
+		if err13 != nil {
+			Usage()
+			return
+		}
+		factory14 := thrift.NewTSimpleJSONProtocolFactory()
+		jsProt15 := factory14.GetProtocol(mbTrans12)
+		containerStruct0 := scribe.NewLogWithResultsArgs()
+		err16 := containerStruct0.ReadField1(jsProt15)
+		if err16 != nil {
+			Usage()
+			return
+		}
+		argvalue0 := containerStruct0.Messages
+		value0 := argvalue0
+		fmt.Print(client.LogWithResults(value0))
+		fmt.Print("\n")
+		break
 	case "":
 		Usage()
 		break
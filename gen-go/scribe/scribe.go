@@ -19,6 +19,11 @@ type Scribe interface {
 	// Parameters:
 	//  - Messages
 	Log(messages []*LogEntry) (r ResultCode, err error)
+	// Like Log but returns a result for each entry, in the same order.
+	// Entries with status TRY_LATER weren't published and should be retried.
+	// Parameters:
+	//  - Messages
+	LogWithResults(messages []*LogEntry) (r []*EntryResult, err error)
 }
 
 type ScribeClient struct {
@@ -116,6 +121,77 @@ func (p *ScribeClient) recvLog() (value ResultCode, err error) {
 	return
 }
 
+// Like Log but returns a result for each entry, in the same order.
+// Entries with status TRY_LATER weren't published and should be retried.
+// Parameters:
+//  - Messages
+func (p *ScribeClient) LogWithResults(messages []*LogEntry) (r []*EntryResult, err error) {
+	if err = p.sendLogWithResults(messages); err != nil {
+		return
+	}
+	return p.recvLogWithResults()
+}
+
+func (p *ScribeClient) sendLogWithResults(messages []*LogEntry) (err error) {
+	oprot := p.OutputProtocol
+	if oprot == nil {
+		oprot = p.ProtocolFactory.GetProtocol(p.Transport)
+		p.OutputProtocol = oprot
+	}
+	p.SeqId++
+	if err = oprot.WriteMessageBegin("LogWithResults", thrift.CALL, p.SeqId); err != nil {
+		return
+	}
+	args := LogWithResultsArgs{
+		Messages: messages,
+	}
+	if err = args.Write(oprot); err != nil {
+		return
+	}
+	if err = oprot.WriteMessageEnd(); err != nil {
+		return
+	}
+	return oprot.Flush()
+}
+
+func (p *ScribeClient) recvLogWithResults() (value []*EntryResult, err error) {
+	iprot := p.InputProtocol
+	if iprot == nil {
+		iprot = p.ProtocolFactory.GetProtocol(p.Transport)
+		p.InputProtocol = iprot
+	}
+	_, mTypeId, seqId, err := iprot.ReadMessageBegin()
+	if err != nil {
+		return
+	}
+	if mTypeId == thrift.EXCEPTION {
+		error2 := thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, "Unknown Exception")
+		var error3 error
+		error3, err = error2.Read(iprot)
+		if err != nil {
+			return
+		}
+		if err = iprot.ReadMessageEnd(); err != nil {
+			return
+		}
+		err = error3
+		return
+	}
+	if p.SeqId != seqId {
+		err = thrift.NewTApplicationException(thrift.BAD_SEQUENCE_ID, "LogWithResults failed: out of sequence response")
+		return
+	}
+	result := LogWithResultsResult{}
+	if err = result.Read(iprot); err != nil {
+		return
+	}
+	if err = iprot.ReadMessageEnd(); err != nil {
+		return
+	}
+	value = result.GetSuccess()
+	return
+}
+
 type ScribeProcessor struct {
 	processorMap map[string]thrift.TProcessorFunction
 	handler      Scribe
@@ -136,9 +212,10 @@ func (p *ScribeProcessor) ProcessorMap() map[string]thrift.TProcessorFunction {
 
 func NewScribeProcessor(handler Scribe) *ScribeProcessor {
 
-	self2 := &ScribeProcessor{handler: handler, processorMap: make(map[string]thrift.TProcessorFunction)}
-	self2.processorMap["Log"] = &scribeProcessorLog{handler: handler}
-	return self2
+	self4 := &ScribeProcessor{handler: handler, processorMap: make(map[string]thrift.TProcessorFunction)}
+	self4.processorMap["Log"] = &scribeProcessorLog{handler: handler}
+	self4.processorMap["LogWithResults"] = &scribeProcessorLogWithResults{handler: handler}
+	return self4
 }
 
 func (p *ScribeProcessor) Process(iprot, oprot thrift.TProtocol) (success bool, err thrift.TException) {
@@ -151,12 +228,12 @@ func (p *ScribeProcessor) Process(iprot, oprot thrift.TProtocol) (success bool,
 	}
 	iprot.Skip(thrift.STRUCT)
 	iprot.ReadMessageEnd()
-	x3 := thrift.NewTApplicationException(thrift.UNKNOWN_METHOD, "Unknown function "+name)
+	x5 := thrift.NewTApplicationException(thrift.UNKNOWN_METHOD, "Unknown function "+name)
 	oprot.WriteMessageBegin(name, thrift.EXCEPTION, seqId)
-	x3.Write(oprot)
+	x5.Write(oprot)
 	oprot.WriteMessageEnd()
 	oprot.Flush()
-	return false, x3
+	return false, x5
 
 }
 
@@ -208,6 +285,54 @@ func (p *scribeProcessorLog) Process(seqId int32, iprot, oprot thrift.TProtocol)
 	return true, err
 }
 
+type scribeProcessorLogWithResults struct {
+	handler Scribe
+}
+
+func (p *scribeProcessorLogWithResults) Process(seqId int32, iprot, oprot thrift.TProtocol) (success bool, err thrift.TException) {
+	args := LogWithResultsArgs{}
+	if err = args.Read(iprot); err != nil {
+		iprot.ReadMessageEnd()
+		x := thrift.NewTApplicationException(thrift.PROTOCOL_ERROR, err.Error())
+		oprot.WriteMessageBegin("LogWithResults", thrift.EXCEPTION, seqId)
+		x.Write(oprot)
+		oprot.WriteMessageEnd()
+		oprot.Flush()
+		return false, err
+	}
+
+	iprot.ReadMessageEnd()
+	result := LogWithResultsResult{}
+	var retval []*EntryResult
+	var err2 error
+	if retval, err2 = p.handler.LogWithResults(args.Messages); err2 != nil {
+		x := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "Internal error processing LogWithResults: "+err2.Error())
+		oprot.WriteMessageBegin("LogWithResults", thrift.EXCEPTION, seqId)
+		x.Write(oprot)
+		oprot.WriteMessageEnd()
+		oprot.Flush()
+		return true, err2
+	} else {
+		result.Success = retval
+	}
+	if err2 = oprot.WriteMessageBegin("LogWithResults", thrift.REPLY, seqId); err2 != nil {
+		err = err2
+	}
+	if err2 = result.Write(oprot); err == nil && err2 != nil {
+		err = err2
+	}
+	if err2 = oprot.WriteMessageEnd(); err == nil && err2 != nil {
+		err = err2
+	}
+	if err2 = oprot.Flush(); err == nil && err2 != nil {
+		err = err2
+	}
+	if err != nil {
+		return
+	}
+	return true, err
+}
+
 // HELPER FUNCTIONS AND STRUCTURES
 
 type LogArgs struct {
@@ -261,11 +386,11 @@ func (p *LogArgs) ReadField1(iprot thrift.TProtocol) error {
 	tSlice := make([]*LogEntry, 0, size)
 	p.Messages = tSlice
 	for i := 0; i < size; i++ {
-		_elem4 := &LogEntry{}
-		if err := _elem4.Read(iprot); err != nil {
-			return fmt.Errorf("%T error reading struct: %s", _elem4, err)
+		_elem6 := &LogEntry{}
+		if err := _elem6.Read(iprot); err != nil {
+			return fmt.Errorf("%T error reading struct: %s", _elem6, err)
 		}
-		p.Messages = append(p.Messages, _elem4)
+		p.Messages = append(p.Messages, _elem6)
 	}
 	if err := iprot.ReadListEnd(); err != nil {
 		return fmt.Errorf("error reading list end: %s", err)
@@ -416,3 +541,228 @@ func (p *LogResult) String() string {
 	}
 	return fmt.Sprintf("LogResult(%+v)", *p)
 }
+
+type LogWithResultsArgs struct {
+	Messages []*LogEntry `thrift:"messages,1" json:"messages"`
+}
+
+func NewLogWithResultsArgs() *LogWithResultsArgs {
+	return &LogWithResultsArgs{}
+}
+
+func (p *LogWithResultsArgs) GetMessages() []*LogEntry {
+	return p.Messages
+}
+func (p *LogWithResultsArgs) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsArgs) ReadField1(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([]*LogEntry, 0, size)
+	p.Messages = tSlice
+	for i := 0; i < size; i++ {
+		_elem7 := &LogEntry{}
+		if err := _elem7.Read(iprot); err != nil {
+			return fmt.Errorf("%T error reading struct: %s", _elem7, err)
+		}
+		p.Messages = append(p.Messages, _elem7)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsArgs) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("LogWithResults_args"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsArgs) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("messages", thrift.LIST, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:messages: %s", p, err)
+	}
+	if err := oprot.WriteListBegin(thrift.STRUCT, len(p.Messages)); err != nil {
+		return fmt.Errorf("error writing list begin: %s", err)
+	}
+	for _, v := range p.Messages {
+		if err := v.Write(oprot); err != nil {
+			return fmt.Errorf("%T error writing struct: %s", v, err)
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return fmt.Errorf("error writing list end: %s", err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:messages: %s", p, err)
+	}
+	return err
+}
+
+func (p *LogWithResultsArgs) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("LogWithResultsArgs(%+v)", *p)
+}
+
+type LogWithResultsResult struct {
+	Success []*EntryResult `thrift:"success,0" json:"success"`
+}
+
+func NewLogWithResultsResult() *LogWithResultsResult {
+	return &LogWithResultsResult{}
+}
+
+var LogWithResultsResult_Success_DEFAULT []*EntryResult
+
+func (p *LogWithResultsResult) GetSuccess() []*EntryResult {
+	if !p.IsSetSuccess() {
+		return LogWithResultsResult_Success_DEFAULT
+	}
+	return p.Success
+}
+func (p *LogWithResultsResult) IsSetSuccess() bool {
+	return p.Success != nil
+}
+
+func (p *LogWithResultsResult) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 0:
+			if err := p.ReadField0(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsResult) ReadField0(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([]*EntryResult, 0, size)
+	p.Success = tSlice
+	for i := 0; i < size; i++ {
+		_elem8 := &EntryResult{}
+		if err := _elem8.Read(iprot); err != nil {
+			return fmt.Errorf("%T error reading struct: %s", _elem8, err)
+		}
+		p.Success = append(p.Success, _elem8)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsResult) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("LogWithResults_result"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField0(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *LogWithResultsResult) writeField0(oprot thrift.TProtocol) (err error) {
+	if p.IsSetSuccess() {
+		if err := oprot.WriteFieldBegin("success", thrift.LIST, 0); err != nil {
+			return fmt.Errorf("%T write field begin error 0:success: %s", p, err)
+		}
+		if err := oprot.WriteListBegin(thrift.STRUCT, len(p.Success)); err != nil {
+			return fmt.Errorf("error writing list begin: %s", err)
+		}
+		for _, v := range p.Success {
+			if err := v.Write(oprot); err != nil {
+				return fmt.Errorf("%T error writing struct: %s", v, err)
+			}
+		}
+		if err := oprot.WriteListEnd(); err != nil {
+			return fmt.Errorf("error writing list end: %s", err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 0:success: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *LogWithResultsResult) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("LogWithResultsResult(%+v)", *p)
+}
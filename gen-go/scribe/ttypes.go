@@ -46,6 +46,81 @@ func ResultCodeFromString(s string) (ResultCode, error) {
 
 func ResultCodePtr(v ResultCode) *ResultCode { return &v }
 
+// What happened to one entry given to LogWithResults
+type EntryStatus int64
+
+const (
+	EntryStatus_PUBLISHED    EntryStatus = 0
+	EntryStatus_DEBOUNCED    EntryStatus = 1
+	EntryStatus_PARTIAL      EntryStatus = 2
+	EntryStatus_TRY_LATER    EntryStatus = 3
+	EntryStatus_INVALID      EntryStatus = 4
+	EntryStatus_STALE        EntryStatus = 5
+	EntryStatus_UNAUTHORIZED EntryStatus = 6
+	EntryStatus_DUPLICATE    EntryStatus = 7
+	EntryStatus_FILTERED     EntryStatus = 8
+	EntryStatus_TOO_LARGE    EntryStatus = 9
+	EntryStatus_RATE_LIMITED EntryStatus = 10
+)
+
+func (p EntryStatus) String() string {
+	switch p {
+	case EntryStatus_PUBLISHED:
+		return "EntryStatus_PUBLISHED"
+	case EntryStatus_DEBOUNCED:
+		return "EntryStatus_DEBOUNCED"
+	case EntryStatus_PARTIAL:
+		return "EntryStatus_PARTIAL"
+	case EntryStatus_TRY_LATER:
+		return "EntryStatus_TRY_LATER"
+	case EntryStatus_INVALID:
+		return "EntryStatus_INVALID"
+	case EntryStatus_STALE:
+		return "EntryStatus_STALE"
+	case EntryStatus_UNAUTHORIZED:
+		return "EntryStatus_UNAUTHORIZED"
+	case EntryStatus_DUPLICATE:
+		return "EntryStatus_DUPLICATE"
+	case EntryStatus_FILTERED:
+		return "EntryStatus_FILTERED"
+	case EntryStatus_TOO_LARGE:
+		return "EntryStatus_TOO_LARGE"
+	case EntryStatus_RATE_LIMITED:
+		return "EntryStatus_RATE_LIMITED"
+	}
+	return "<UNSET>"
+}
+
+func EntryStatusFromString(s string) (EntryStatus, error) {
+	switch s {
+	case "EntryStatus_PUBLISHED":
+		return EntryStatus_PUBLISHED, nil
+	case "EntryStatus_DEBOUNCED":
+		return EntryStatus_DEBOUNCED, nil
+	case "EntryStatus_PARTIAL":
+		return EntryStatus_PARTIAL, nil
+	case "EntryStatus_TRY_LATER":
+		return EntryStatus_TRY_LATER, nil
+	case "EntryStatus_INVALID":
+		return EntryStatus_INVALID, nil
+	case "EntryStatus_STALE":
+		return EntryStatus_STALE, nil
+	case "EntryStatus_UNAUTHORIZED":
+		return EntryStatus_UNAUTHORIZED, nil
+	case "EntryStatus_DUPLICATE":
+		return EntryStatus_DUPLICATE, nil
+	case "EntryStatus_FILTERED":
+		return EntryStatus_FILTERED, nil
+	case "EntryStatus_TOO_LARGE":
+		return EntryStatus_TOO_LARGE, nil
+	case "EntryStatus_RATE_LIMITED":
+		return EntryStatus_RATE_LIMITED, nil
+	}
+	return EntryStatus(0), fmt.Errorf("not a valid EntryStatus string")
+}
+
+func EntryStatusPtr(v EntryStatus) *EntryStatus { return &v }
+
 type LogEntry struct {
 	Category string `thrift:"category,1" json:"category"`
 	Message  string `thrift:"message,2" json:"message"`
@@ -167,3 +242,197 @@ func (p *LogEntry) String() string {
 	}
 	return fmt.Sprintf("LogEntry(%+v)", *p)
 }
+
+// Result for one entry. An entry can hold several messages, which are counted
+// as published (including held for debouncing) or dropped. reason is why the
+// last dropped one was dropped, as in the dropped.* metrics.
+type EntryResult struct {
+	Status    EntryStatus `thrift:"status,1" json:"status"`
+	Published int32       `thrift:"published,2" json:"published"`
+	Dropped   int32       `thrift:"dropped,3" json:"dropped"`
+	Reason    string      `thrift:"reason,4" json:"reason"`
+}
+
+func NewEntryResult() *EntryResult {
+	return &EntryResult{}
+}
+
+func (p *EntryResult) GetStatus() EntryStatus {
+	return p.Status
+}
+
+func (p *EntryResult) GetPublished() int32 {
+	return p.Published
+}
+
+func (p *EntryResult) GetDropped() int32 {
+	return p.Dropped
+}
+
+func (p *EntryResult) GetReason() string {
+	return p.Reason
+}
+func (p *EntryResult) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *EntryResult) ReadField1(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 1: %s", err)
+	} else {
+		temp := EntryStatus(v)
+		p.Status = temp
+	}
+	return nil
+}
+
+func (p *EntryResult) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.Published = v
+	}
+	return nil
+}
+
+func (p *EntryResult) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 3: %s", err)
+	} else {
+		p.Dropped = v
+	}
+	return nil
+}
+
+func (p *EntryResult) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 4: %s", err)
+	} else {
+		p.Reason = v
+	}
+	return nil
+}
+
+func (p *EntryResult) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("EntryResult"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *EntryResult) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("status", thrift.I32, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:status: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Status)); err != nil {
+		return fmt.Errorf("%T.status (1) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:status: %s", p, err)
+	}
+	return err
+}
+
+func (p *EntryResult) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("published", thrift.I32, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:published: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Published)); err != nil {
+		return fmt.Errorf("%T.published (2) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:published: %s", p, err)
+	}
+	return err
+}
+
+func (p *EntryResult) writeField3(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("dropped", thrift.I32, 3); err != nil {
+		return fmt.Errorf("%T write field begin error 3:dropped: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Dropped)); err != nil {
+		return fmt.Errorf("%T.dropped (3) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 3:dropped: %s", p, err)
+	}
+	return err
+}
+
+func (p *EntryResult) writeField4(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("reason", thrift.STRING, 4); err != nil {
+		return fmt.Errorf("%T write field begin error 4:reason: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.Reason)); err != nil {
+		return fmt.Errorf("%T.reason (4) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 4:reason: %s", p, err)
+	}
+	return err
+}
+
+func (p *EntryResult) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("EntryResult(%+v)", *p)
+}
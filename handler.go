@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
+	"sync/atomic"
 	"time"
 
 	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
@@ -19,12 +21,21 @@ type Handler struct {
 	apiKey         string
 	sd             statsd.Statsd
 	shardedApiKeys []string
+	config         atomic.Value // *Config
+	keyring        atomic.Value // *Keyring
+	script         *ScriptEngine
+	limiters       *rateLimiters
+	debouncer      *debouncer
+	dedup          dedupStore
+	decompressor   *decompressor
+	hostname       string
 }
 
 func NewHandler(redisAddr string, redisDB, redisIdleTimeout, numPubAPIShards int, apiKey string, sd statsd.Statsd) (*Handler, error) {
 	h := &Handler{
-		apiKey: apiKey,
-		sd:     sd,
+		apiKey:   apiKey,
+		sd:       sd,
+		limiters: newRateLimiters(),
 	}
 	h.redisClient = redis.NewClient(&redis.Options{
 		Addr:         redisAddr,
@@ -40,91 +51,525 @@ func NewHandler(redisAddr string, redisDB, redisIdleTimeout, numPubAPIShards int
 		key := fmt.Sprintf("%s.%d", apiKey, i)
 		h.shardedApiKeys = append(h.shardedApiKeys, key)
 	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	h.hostname = hostname
+	h.debouncer = newDebouncer(h.publishDebounced, sd)
+	h.debouncer.run()
 	return h, nil
 }
 
-func scribeEntriesToBroadcastCommand(messages []*scribe.LogEntry, sd statsd.Statsd) (*centrifugoRedisRequest, int64, error) {
+// SetConfig replaces the processing rules used for subsequent batches. It is safe
+// to call while the handler is serving.
+func (h *Handler) SetConfig(cfg *Config) {
+	h.config.Store(cfg)
+}
+
+// SetKeyring replaces the keys used to verify signed messages. It is safe to
+// call while the handler is serving.
+func (h *Handler) SetKeyring(k *Keyring) {
+	h.keyring.Store(k)
+}
+
+// SetScript sets a script to run on every message. It must be called before the
+// handler starts serving.
+func (h *Handler) SetScript(s *ScriptEngine) {
+	h.script = s
+}
+
+// EnableDedup drops messages already seen within window. Seen messages are kept in
+// a local cache of up to maxEntries or, if shared is set, in redis under keyPrefix
+// so that all scriber instances see them. It must be called before the handler
+// starts serving.
+func (h *Handler) EnableDedup(window time.Duration, maxEntries int, shared bool, keyPrefix string) error {
+	if maxEntries < 1 {
+		return fmt.Errorf("dedup max entries must be positive, got %d", maxEntries)
+	}
+	if shared {
+		h.dedup = &redisDedupStore{client: h.redisClient, prefix: keyPrefix, window: window}
+	} else {
+		h.dedup = newLocalDedupStore(window, maxEntries)
+	}
+	return nil
+}
+
+// SetMaxDecompressedSize enables compressed messages, dropping any that decompress
+// to more than limit bytes. It must be called before the handler starts serving.
+func (h *Handler) SetMaxDecompressedSize(limit int64) error {
+	d, err := newDecompressor(limit)
+	if err != nil {
+		return err
+	}
+	h.decompressor = d
+	return nil
+}
+
+// FlushDebounced publishes debounced messages without waiting for their windows
+// to end, so they aren't lost when shutting down.
+func (h *Handler) FlushDebounced() {
+	h.debouncer.flushAll()
+}
+
+// redisHealth returns an error if redis can't be reached
+func (h *Handler) redisHealth() error {
+	return h.redisClient.Ping().Err()
+}
+
+// currentConfig returns the active config which may be nil if none was given
+func (h *Handler) currentConfig() *Config {
+	cfg, _ := h.config.Load().(*Config)
+	return cfg
+}
+
+// currentKeyring returns the active keyring which may be nil if none was given
+func (h *Handler) currentKeyring() *Keyring {
+	k, _ := h.keyring.Load().(*Keyring)
+	return k
+}
+
+func scribeEntriesToBroadcastCommand(messages []*scribe.LogEntry, batch *batchContext, sd statsd.Statsd) (*centrifugoRedisRequest, int64, error) {
 	var req centrifugoRedisRequest
 	req.Data = make([]centrifugoApiCommand, 0, len(messages))
+	batch.entries = make([]entryResult, len(messages))
 
 	var totalBroadcasts int64
 
-	for _, m := range messages {
-		msg, err := parseMessage([]byte(m.Message))
-		if merr, ok := err.(*MessageStaleErr); ok {
-			glog.Warningf("Dropping stale message: %s", merr)
-			sd.Incr("dropped.stale_ttl", 1)
+	for i, m := range messages {
+		entry := &batch.entries[i]
+		raw, compressed, err := batch.decompressor.decompress([]byte(m.Message), batch.config.compressionFor(m.Category))
+		if compressed {
+			sd.Incr("bytes.compressed", int64(len(m.Message)))
+		}
+		if _, ok := err.(*errTooLarge); ok {
+			glog.Warningf("Dropping compression bomb in category %s: %s", m.Category, err)
+			batch.drop("decompressed_too_large", 1, sd)
+			batch.dropped++
+			entry.drop(batch.dropReason)
+			continue
+		}
+		if err != nil {
+			glog.Warningf("Failed to decompress message in category %s, Dropping message. err: %s", m.Category, err)
+			batch.drop("decompress_fail", 1, sd)
+			batch.dropped++
+			entry.drop(batch.dropReason)
+			continue
+		}
+		if compressed {
+			sd.Incr("bytes.raw", int64(len(raw)))
+		}
+
+		encoding := batch.config.encodingFor(m.Category)
+		for _, d := range decodeEnvelopes(raw, encoding) {
+			totalBroadcasts += appendEnvelope(&req, d, m.Category, entry, batch, sd)
+		}
+	}
+
+	return &req, totalBroadcasts, nil
+}
+
+// appendEnvelope is appendBroadcastCommands counting whether the envelope was
+// accepted or dropped in the batch and the entry it came from
+func appendEnvelope(req *centrifugoRedisRequest, d decodedMessage, category string, entry *entryResult, batch *batchContext, sd statsd.Statsd) int64 {
+	published, debounced := len(req.Data), batch.debounced
+	batch.dropReason = ""
+	broadcasts := appendBroadcastCommands(req, d, category, batch, sd)
+	switch {
+	case len(req.Data) > published:
+		batch.accepted++
+		entry.published++
+	case batch.debounced > debounced:
+		batch.accepted++
+		entry.debounced++
+	default:
+		batch.dropped++
+		entry.drop(batch.dropReason)
+	}
+	return broadcasts
+}
+
+// batchContext describes where and when a batch of Scribe entries was received
+// and what rules to process it with.
+type batchContext struct {
+	config       *Config
+	script       *ScriptEngine
+	limiters     *rateLimiters
+	debouncer    *debouncer
+	dedup        dedupStore
+	keyring      *Keyring
+	decompressor *decompressor
+	receivedAt   time.Time
+	hostname     string
+	// queue is the centrifugo API queue the batch is pushed to, and shard its index
+	queue string
+	shard int
+
+	// dedupKeys collects the keys recorded in dedup for this batch so they can be
+	// forgotten if it isn't published
+	dedupKeys []string
+	// sizes is how much data the batch is publishing, for the batch size limits
+	sizes batchSizes
+	// deadLetters collects messages over size limits to push once the batch is published
+	deadLetters []*deadLetter
+	// accepted and dropped count the messages in the batch that will and won't
+	// be published, with debounced counting those held for a debounce window
+	accepted, dropped, debounced int
+	// entries is what happened to each Scribe entry in the batch, in order
+	entries []entryResult
+	// dropReason is why the last message was dropped, as in its dropped.* metric
+	dropReason string
+}
+
+// drop counts n messages dropped for reason, remembering it as the reason for
+// the message being processed
+func (b *batchContext) drop(reason string, n int64, sd statsd.Statsd) {
+	sd.Incr("dropped."+reason, n)
+	b.dropReason = reason
+}
+
+// appendBroadcastCommands runs a single decoded message envelope through the
+// configured processing and appends the resulting broadcasts to req.
+// It returns the number of channels broadcast to.
+func appendBroadcastCommands(req *centrifugoRedisRequest, d decodedMessage, category string, batch *batchContext, sd statsd.Statsd) int64 {
+	msg, err, raw := d.msg, d.err, d.raw
+	if merr, ok := err.(*MessageStaleErr); ok {
+		glog.Warningf("Dropping stale message: %s", merr)
+		batch.drop("stale_ttl", 1, sd)
+		return 0
+	}
+	if err != nil {
+		glog.Warningf("Failed to parse incoming message, Dropping message: %q, err: %s", raw, err)
+		batch.drop("invalid_format", 1, sd)
+		return 0
+	}
+
+	msg.expandUserChannels()
+
+	cfg := batch.config
+	// A signature that passes verification covers the channels it was made for
+	var signedChannels []string
+	if msg.KeyID != "" || msg.Signature != "" {
+		signedChannels = msg.Channels
+	}
+	if err := batch.keyring.verify(msg, cfg.signatureRequired(category, msg.Channels)); err != nil {
+		glog.Warningf("Dropping message that failed signature check: %s, err: %s", raw, err)
+		if err == errUnsigned {
+			batch.drop("unsigned", 1, sd)
+		} else {
+			batch.drop("bad_signature", 1, sd)
+		}
+		return 0
+	}
+
+	// Oversized envelopes aren't worth processing any further
+	if limits, namespace := cfg.envelopeOverLimit(msg, len(raw)); limits != nil {
+		overLimit(msg, limitMessage, limits, category, namespace, batch, sd)
+		return 0
+	}
+
+	if batch.dedup != nil {
+		key := dedupKey(msg)
+		dup, err := batch.dedup.seen(key)
+		if err != nil {
+			// Better to risk a duplicate than to lose the message
+			glog.Warningf("Failed to check for duplicate message, publishing anyway. err: %s", err)
+			sd.Incr("error.dedup_fail", 1)
+		} else if dup {
+			batch.drop("duplicate", 1, sd)
+			return 0
+		} else {
+			batch.dedupKeys = append(batch.dedupKeys, key)
+		}
+	}
+
+	rejectedBy, err := cfg.filters().check(category, msg)
+	if err != nil {
+		glog.Warningf("Failed to filter message, Dropping message: %s, err: %s", raw, err)
+		batch.drop("invalid_format", 1, sd)
+		return 0
+	}
+	if rejectedBy != "" {
+		batch.drop("filter."+rejectedBy, 1, sd)
+		return 0
+	}
+
+	msgs := []*centrifugoBroadcastParams{msg}
+	if batch.script != nil {
+		msgs, err = batch.script.Process(category, msg)
+		if err != nil {
+			glog.Warningf("Script failed, Dropping message: %s, err: %s", raw, err)
+			batch.drop("script_error", 1, sd)
+			return 0
+		}
+		if len(msgs) < 1 {
+			batch.drop("script", 1, sd)
+			return 0
+		}
+
+		// The script can route messages to channels that need a signature, which
+		// the input's has to cover as it would have had they been sent there
+		signed := msgs[:0]
+		for _, out := range msgs {
+			if cfg.signatureRequired(category, out.Channels) && !signatureCovers(signedChannels, out.Channels) {
+				glog.Warningf("Dropping script output to %v not covered by a signature: %s", out.Channels, raw)
+				batch.drop("unsigned", 1, sd)
+				continue
+			}
+			signed = append(signed, out)
+		}
+		if msgs = signed; len(msgs) < 1 {
+			return 0
+		}
+	}
+
+	var broadcasts int64
+	for _, part := range cfg.splitByNamespace(msgs) {
+		namespace := channelNamespace(part.Channels[0])
+		if !cfg.sampleFor(namespace).keep(part) {
+			batch.drop("sampled", 1, sd)
 			continue
 		}
+
+		part.Data, err = applyTransforms(part.Data, cfg.transformsFor(category, namespace))
 		if err != nil {
-			glog.Warningf("Failed to parse incoming JSON, Dropping message: %s, err: %s", m.Message, err)
-			sd.Incr("dropped.invalid_format", 1)
+			glog.Warningf("Failed to transform message, Dropping message: %s, err: %s", raw, err)
+			batch.drop("transform_fail", 1, sd)
+			continue
+		}
+
+		if enrich := cfg.enrichFor(category, namespace); enrich != nil {
+			enriched, err := enrich.enrich(part.Data, category, batch)
+			if err != nil {
+				// Enrichment is only a debugging aid so deliver the message without it
+				glog.Warningf("Failed to enrich message, publishing as is: %s, err: %s", raw, err)
+				sd.Incr("error.enrich_fail", 1)
+			} else {
+				part.Data = enriched
+			}
+		}
+
+		limits := cfg.limitsFor(namespace)
+		if limit := limits.exceeded(part, len(raw)); limit != "" {
+			if !overLimit(part, limit, limits, category, namespace, batch, sd) {
+				continue
+			}
+		}
+
+		allowed := batch.limiters.limit(namespace, part.Channels, cfg.rateLimitFor(namespace))
+		if limited := len(part.Channels) - len(allowed); limited > 0 {
+			sd.Incr("rate_limited.channels", int64(limited))
+			if len(allowed) < 1 {
+				batch.drop("rate_limited", 1, sd)
+				continue
+			}
+			part.Channels = allowed
+		}
+
+		if batch.debouncer.add(part, cfg.debounceFor(namespace), batch.queue, batch.receivedAt) {
+			// Will be published when its debounce window ends
+			batch.debounced++
 			continue
 		}
 
-		totalBroadcasts += int64(len(msg.Channels))
+		nsLimits := cfg.namespaceLimits(namespace)
+		if !batch.sizes.fits(len(part.Data), namespace, cfg.globalLimits(), nsLimits) {
+			if !overLimit(part, limitBatch, limits, category, namespace, batch, sd) {
+				continue
+			}
+			if !batch.sizes.fits(len(part.Data), namespace, cfg.globalLimits(), nsLimits) {
+				// Even the reference doesn't fit
+				batch.drop("too_large."+limitBatch, 1, sd)
+				continue
+			}
+		}
+		batch.sizes.add(len(part.Data), namespace)
+
+		broadcasts += int64(len(part.Channels))
 
 		req.Data = append(req.Data, centrifugoApiCommand{
 			Method: "broadcast",
-			Params: *msg,
+			Params: *part,
 		})
 	}
+	return broadcasts
+}
 
-	return &req, totalBroadcasts, nil
+// overLimit applies the action for a message over a size limit. It returns true
+// if the message should still be published, with its data truncated.
+func overLimit(msg *centrifugoBroadcastParams, limit string, limits *sizeLimitConfig, category, namespace string, batch *batchContext, sd statsd.Statsd) bool {
+	switch limits.action() {
+	case limitTruncate:
+		ref, err := truncateToRef(msg)
+		if err != nil {
+			glog.Warningf("Failed to truncate message over %s size limit, Dropping message. err: %s", limit, err)
+			break
+		}
+		msg.Data = ref
+		sd.Incr("truncated."+limit, 1)
+		return true
+	case limitDeadLetter:
+		batch.deadLetters = append(batch.deadLetters, &deadLetter{
+			key:       limits.deadLetterKey(),
+			Limit:     limit,
+			Category:  category,
+			Namespace: namespace,
+			Size:      len(msg.Data),
+			Params:    *msg,
+		})
+		sd.Incr("dead_lettered."+limit, 1)
+		batch.dropReason = "dead_lettered." + limit
+		return false
+	}
+	batch.drop("too_large."+limit, 1, sd)
+	return false
 }
 
 // pickQueueKey chooses a sharded queue at random if we are sharded otherwise
-// returns single default queue.
+// returns single default queue. The shard index is returned along with the key
+// and is always 0 when not sharded.
 // We could do nice sharding based on channel etc. but that breaks efficiency of broadcast
 // and Scribe transport already destroys any order guarantee we might hope to preserve
-func (h *Handler) pickQueueKey() string {
+func (h *Handler) pickQueueKey() (string, int) {
 	if len(h.shardedApiKeys) < 1 {
-		return h.apiKey
+		return h.apiKey, 0
 	}
 
 	shardID := rand.Intn(len(h.shardedApiKeys))
-	return h.shardedApiKeys[shardID]
+	return h.shardedApiKeys[shardID], shardID
 }
 
 func (h *Handler) Log(messages []*scribe.LogEntry) (r scribe.ResultCode, err error) {
+	r, _ = h.logBatch(messages)
+	return r, nil
+}
+
+// logBatch publishes a batch of Scribe entries, returning the result for the
+// client and the batch so callers can see what happened to each message.
+func (h *Handler) logBatch(messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext) {
+	return h.logBatchConfig(h.currentConfig(), messages)
+}
 
+// logBatchConfig is logBatch with the processing rules in cfg, such as those
+// for the listener the batch was received on
+func (h *Handler) logBatchConfig(cfg *Config, messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext) {
 	if len(messages) < 1 {
-		return scribe.ResultCode_OK, nil
+		return scribe.ResultCode_OK, &batchContext{}
 	}
 
-	req, totalBroadcasts, err := scribeEntriesToBroadcastCommand(messages, h.sd)
+	batch := h.newBatch(cfg)
+	req, totalBroadcasts, err := scribeEntriesToBroadcastCommand(messages, batch, h.sd)
 	if err != nil {
 		// Assume parse errors are fatal and client retry is pointless
-		return scribe.ResultCode_OK, nil
+		return scribe.ResultCode_OK, batch
+	}
+	if err := h.publishBatch(req, totalBroadcasts, batch); err != nil {
+		return scribe.ResultCode_TRY_LATER, batch
 	}
+	return scribe.ResultCode_OK, batch
+}
+
+// newBatch starts a batch processed with the rules in cfg
+func (h *Handler) newBatch(cfg *Config) *batchContext {
+	queue, shard := h.pickQueueKey()
+	return &batchContext{
+		config:       cfg,
+		keyring:      h.currentKeyring(),
+		script:       h.script,
+		limiters:     h.limiters,
+		debouncer:    h.debouncer,
+		dedup:        h.dedup,
+		decompressor: h.decompressor,
+		receivedAt:   time.Now(),
+		hostname:     h.hostname,
+		queue:        queue,
+		shard:        shard,
+	}
+}
 
+// publishBatch pushes the broadcasts built for batch. It only returns an error
+// if redis failed and the batch should be retried.
+func (h *Handler) publishBatch(req *centrifugoRedisRequest, totalBroadcasts int64, batch *batchContext) error {
 	if len(req.Data) < 1 {
 		// Nothing to publish in this batch - all expired probably
 		glog.Info("No publishable events in batch")
-		return scribe.ResultCode_OK, nil
+		h.pushDeadLetters(batch.deadLetters)
+		return nil
+	}
+
+	if err := h.push(batch.queue, req, totalBroadcasts); err != nil {
+		// Downstream should retry, so the retried messages must not look like duplicates
+		if h.dedup != nil {
+			if err := h.dedup.forget(batch.dedupKeys); err != nil {
+				glog.Errorf("Failed to forget dedup keys for retried batch. err: %s", err)
+				h.sd.Incr("error.dedup_fail", 1)
+			}
+		}
+		return err
 	}
 
+	// Only once published, otherwise Scribe's retry would dead letter them again
+	h.pushDeadLetters(batch.deadLetters)
+	return nil
+}
+
+// push encodes req and pushes it onto a centrifugo API queue. An error is only
+// returned if redis failed; requests that can't be encoded are dropped since
+// retrying them would fail forever.
+func (h *Handler) push(queue string, req *centrifugoRedisRequest, totalBroadcasts int64) error {
 	jsonBytes, err := json.Marshal(req)
 	if err != nil {
 		glog.Errorf("Failed to encode JSON body, dropping %d messages. err: %s", len(req.Data), err)
-		// Still return OK since failing will just cause infinite retries...
 		h.sd.Incr("error.encode_fail", 1)
 		h.sd.Incr("dropped.encode_fail", int64(len(req.Data)))
-		return scribe.ResultCode_OK, nil
+		return nil
 	}
 
 	jsonStr := string(jsonBytes)
 
-	queue := h.pickQueueKey()
 	qSize, err := h.redisClient.RPush(queue, jsonStr).Result()
 	if err != nil {
-		glog.Errorf("Failed to push command to redis, downstream should retry. err: %s", err)
+		glog.Errorf("Failed to push command to redis. err: %s", err)
 		h.sd.Incr("error.redis_publish_fail_temp", 1)
-		return scribe.ResultCode_TRY_LATER, nil
+		return err
 	}
 	h.sd.Incr("broadcasts", totalBroadcasts)
 	h.sd.Incr("published", int64(len(req.Data)))
 	h.sd.Gauge(queue+".queue_length", qSize)
+	return nil
+}
+
+// pushDeadLetters pushes messages over size limits onto their dead letter lists.
+// Scribe has already been told they were accepted so if redis fails they are lost.
+func (h *Handler) pushDeadLetters(letters []*deadLetter) {
+	byKey := make(map[string][]interface{})
+	for _, l := range letters {
+		b, err := json.Marshal(l)
+		if err != nil {
+			glog.Errorf("Failed to encode dead letter, dropping it. err: %s", err)
+			h.sd.Incr("dropped.dead_letter_fail", 1)
+			continue
+		}
+		byKey[l.key] = append(byKey[l.key], string(b))
+	}
+	for key, values := range byKey {
+		if err := h.redisClient.RPush(key, values...).Err(); err != nil {
+			glog.Errorf("Failed to push %d dead letters to redis. err: %s", len(values), err)
+			h.sd.Incr("error.dead_letter_fail", 1)
+			h.sd.Incr("dropped.dead_letter_fail", int64(len(values)))
+		}
+	}
+}
 
-	return scribe.ResultCode_OK, nil
+// publishDebounced pushes messages whose debounce window has ended onto the
+// queue picked for the batch they arrived in, so their enriched shard is right.
+// Scribe has already been told they were accepted so if redis fails they are lost.
+func (h *Handler) publishDebounced(queue string, cmds []centrifugoApiCommand) {
+	var totalBroadcasts int64
+	for _, cmd := range cmds {
+		totalBroadcasts += int64(len(cmd.Params.Channels))
+	}
+	if err := h.push(queue, &centrifugoRedisRequest{Data: cmds}, totalBroadcasts); err != nil {
+		h.sd.Incr("dropped.debounce_publish_fail", int64(len(cmds)))
+	}
 }
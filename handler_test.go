@@ -15,6 +15,7 @@ func TestParsingScribeMessages(t *testing.T) {
 	type testCase struct {
 		name             string
 		input            []*scribe.LogEntry
+		config           *Config
 		expectOut        *centrifugoRedisRequest
 		expectBroadcasts int64
 		expectErr        bool
@@ -53,6 +54,111 @@ func TestParsingScribeMessages(t *testing.T) {
 			expectBroadcasts: 1,
 			expectErr:        false,
 		},
+		{
+			name: "User list expanded to personal channels",
+			input: []*scribe.LogEntry{
+				{
+					Category: "HUBD",
+					Message:  "{\"channels\":[\"foo\"], \"users\":[\"bob\", \"alice\"], \"channel_template\":\"notifications#{user}\", \"data\":{\"foo\": 1234}}",
+				},
+			},
+			expectOut: &centrifugoRedisRequest{
+				Data: []centrifugoApiCommand{
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"foo", "notifications#bob", "notifications#alice"},
+							Data:     json.RawMessage("{\"foo\": 1234}"),
+						},
+					},
+				},
+			},
+			expectBroadcasts: 3,
+			expectErr:        false,
+		},
+		{
+			name: "Transforms split by namespace",
+			input: []*scribe.LogEntry{
+				{
+					Category: "HUBD",
+					Message:  "{\"channels\":[\"public:foo\", \"bar\", \"public:baz\"], \"data\":{\"foo\":1,\"internal\":2}}",
+				},
+				{
+					Category: "HUBD",
+					Message:  "{\"channels\":[\"bar\"], \"data\":[1]}", // can't transform non-object
+				},
+			},
+			config: &Config{
+				Categories: map[string]*routeConfig{
+					"HUBD": {Transforms: []*transformRule{{Op: transformSet, Path: "src", Value: json.RawMessage("\"hub\"")}}},
+				},
+				Namespaces: map[string]*routeConfig{
+					"public": {Transforms: []*transformRule{{Op: transformDrop, Path: "internal"}}},
+				},
+			},
+			expectOut: &centrifugoRedisRequest{
+				Data: []centrifugoApiCommand{
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"public:foo", "public:baz"},
+							Data:     json.RawMessage("{\"foo\":1,\"src\":\"hub\"}"),
+						},
+					},
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"bar"},
+							Data:     json.RawMessage("{\"foo\":1,\"internal\":2,\"src\":\"hub\"}"),
+						},
+					},
+				},
+			},
+			expectBroadcasts: 3,
+			expectErr:        false,
+		},
+		{
+			name: "Multiple envelopes in one entry",
+			input: []*scribe.LogEntry{
+				{
+					Category: "HUBD",
+					Message: fmt.Sprintf("[{\"channels\":[\"foo\"], \"data\":{\"foo\": 1}}, {\"channels\":[\"bar\"], \"data\":{\"ts\": %d, \"ttl\":60}}, {\"channels\":[\"baz\"]}]",
+						now.Add(-1*time.Hour).Unix()),
+				},
+				{
+					Category: "HUBD",
+					Message:  "{\"channels\":[\"foo2\"], \"data\":{\"foo\": 2}}\n{\"channels\":[\"foo3\"], \"data\":{\"foo\": 3}}\n",
+				},
+			},
+			expectOut: &centrifugoRedisRequest{
+				Data: []centrifugoApiCommand{
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"foo"},
+							Data:     json.RawMessage("{\"foo\": 1}"),
+						},
+					},
+					// bar expired, baz invalid
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"foo2"},
+							Data:     json.RawMessage("{\"foo\": 2}"),
+						},
+					},
+					{
+						Method: "broadcast",
+						Params: centrifugoBroadcastParams{
+							Channels: []string{"foo3"},
+							Data:     json.RawMessage("{\"foo\": 3}"),
+						},
+					},
+				},
+			},
+			expectBroadcasts: 3,
+			expectErr:        false,
+		},
 		{
 			name: "Single valid event with OK TTL",
 			input: []*scribe.LogEntry{
@@ -195,8 +301,7 @@ func TestParsingScribeMessages(t *testing.T) {
 				},
 				{
 					Category: "HUBD",
-					Message: fmt.Sprintf("{\"channels\":[\"foo2\"]}", // invalid no data field
-						now.Add(-20*time.Second).Unix()),
+					Message:  "{\"channels\":[\"foo2\"]}", // invalid no data field
 				},
 				{
 					Category: "HUBD",
@@ -231,7 +336,7 @@ func TestParsingScribeMessages(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		out, totalBroadcast, err := scribeEntriesToBroadcastCommand(test.input, &statsd.NoopClient{})
+		out, totalBroadcast, err := scribeEntriesToBroadcastCommand(test.input, &batchContext{config: test.config}, &statsd.NoopClient{})
 		if test.expectErr {
 			if err == nil {
 				t.Errorf("Failed case %s: expected error got nil", test.name)
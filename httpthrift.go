@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// Largest request body accepted over HTTP
+const maxHTTPBodySize = 64 << 20
+
+// thriftHTTPHandler serves a Thrift processor over HTTP POST, one call per
+// request, as sent by Thrift's HTTP client transport.
+type thriftHTTPHandler struct {
+	processor thrift.TProcessor
+	// protocol is the Thrift protocol requests use, or auto to detect it from
+	// each request body
+	protocol string
+	tokens   bearerTokens
+	sd       statsd.Statsd
+}
+
+// bearerTokens are the tokens accepted in `Authorization: Bearer` headers. If
+// there are none no auth is needed.
+type bearerTokens []string
+
+// parseTokens splits a comma separated list of auth tokens
+func parseTokens(list string) bearerTokens {
+	var tokens bearerTokens
+	for _, token := range strings.Split(list, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// authorized reports whether r has one of the accepted tokens
+func (tokens bearerTokens) authorized(r *http.Request) bool {
+	if len(tokens) < 1 {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	given := []byte(strings.TrimPrefix(auth, "Bearer "))
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare(given, []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *thriftHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Thrift requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.tokens.authorized(r) {
+		h.sd.Incr("thrift_http.unauthorized", 1)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	if err != nil {
+		glog.Warningf("Failed to read Thrift HTTP request from %s. err: %s", r.RemoteAddr, err)
+		h.sd.Incr("error.thrift_http_fail", 1)
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+	h.sd.Incr("thrift_http.requests", 1)
+
+	protocol := h.protocol
+	if protocol == detectAuto {
+		protocol = protocolBinary
+		if len(body) > 0 {
+			if detected := protocolByFirstByte(body[0]); detected != "" {
+				protocol = detected
+			}
+		}
+	}
+	factory := protocolFactories[protocol]
+
+	in := thrift.NewTMemoryBufferLen(len(body))
+	in.Write(body)
+	out := thrift.NewTMemoryBuffer()
+	if _, err := h.processor.Process(factory.GetProtocol(in), factory.GetProtocol(out)); err != nil {
+		glog.Warningf("Failed to process Thrift HTTP request from %s. err: %s", r.RemoteAddr, err)
+		h.sd.Incr("error.thrift_http_fail", 1)
+		if out.Len() < 1 {
+			http.Error(w, "Invalid Thrift request", http.StatusBadRequest)
+			return
+		}
+		// Otherwise the processor wrote an exception for the client
+	}
+
+	w.Header().Set("Content-Type", "application/x-thrift")
+	w.Write(out.Bytes())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestThriftHTTP(t *testing.T) {
+	type testCase struct {
+		name     string
+		protocol string
+		token    string
+		expectOK bool
+	}
+
+	received := &countingScribe{received: make(chan string, 1)}
+	server := httptest.NewServer(&thriftHTTPHandler{
+		processor: scribe.NewScribeProcessor(received),
+		protocol:  detectAuto,
+		tokens:    parseTokens("old-token, new-token"),
+		sd:        &statsd.NoopClient{},
+	})
+	defer server.Close()
+
+	tests := []testCase{
+		{"Binary", protocolBinary, "new-token", true},
+		{"Compact with old token", protocolCompact, "old-token", true},
+		{"JSON", protocolJSON, "new-token", true},
+		{"Wrong token", protocolBinary, "bad-token", false},
+		{"No token", protocolBinary, "", false},
+	}
+
+	for _, test := range tests {
+		trans, err := thrift.NewTHttpPostClient(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.token != "" {
+			trans.(*thrift.THttpClient).SetHeader("Authorization", "Bearer "+test.token)
+		}
+		client := scribe.NewScribeClientFactory(trans, protocolFactories[test.protocol])
+		result, err := client.Log([]*scribe.LogEntry{{Category: "test", Message: test.name}})
+		if !test.expectOK {
+			if err == nil {
+				t.Errorf("Failed case %s: expected error got %v", test.name, result)
+			}
+			continue
+		}
+		if err != nil || result != scribe.ResultCode_OK {
+			t.Errorf("Failed case %s: expected OK got %v, err %v", test.name, result, err)
+			continue
+		}
+		if got := <-received.received; got != test.name {
+			t.Errorf("Failed case %s: server received %q", test.name, got)
+		}
+	}
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be refused got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Actions taken on messages over a size limit
+const (
+	limitDrop       = "drop"
+	limitDeadLetter = "dead_letter"
+	limitTruncate   = "truncate"
+)
+
+// Names of the size limits, used in metrics and dead letters
+const (
+	limitMessage = "message"
+	limitData    = "data"
+	limitBatch   = "batch"
+)
+
+// Default for sizeLimitConfig.DeadLetterKey
+const defaultDeadLetterKey = "centrifugo-scriber.dead_letter"
+
+// sizeLimitConfig limits how large messages can be. Sizes are in bytes and 0 means
+// unlimited. MaxMessage applies to each envelope as received (after
+// decompression), MaxData to the data delivered to clients and MaxBatch to the
+// total data published from one Scribe batch.
+//
+// Messages over a limit are dropped, pushed to the DeadLetterKey redis list, or
+// have their data replaced by a small reference to the original.
+type sizeLimitConfig struct {
+	MaxMessage    int    `json:"max_message_bytes"`
+	MaxData       int    `json:"max_data_bytes"`
+	MaxBatch      int    `json:"max_batch_bytes"`
+	Action        string `json:"action"`
+	DeadLetterKey string `json:"dead_letter_key"`
+}
+
+func (c *sizeLimitConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxMessage < 0 || c.MaxData < 0 || c.MaxBatch < 0 {
+		return errors.New("size limits must not be negative")
+	}
+	switch c.Action {
+	case "", limitDrop, limitDeadLetter, limitTruncate:
+	default:
+		return errors.New("size limit action must be drop, dead_letter or truncate")
+	}
+	return nil
+}
+
+// merge returns the limits in c overridden by any set in override. Either may be nil.
+func (c *sizeLimitConfig) merge(override *sizeLimitConfig) *sizeLimitConfig {
+	if override == nil {
+		return c
+	}
+	if c == nil {
+		return override
+	}
+	merged := *c
+	if override.MaxMessage > 0 {
+		merged.MaxMessage = override.MaxMessage
+	}
+	if override.MaxData > 0 {
+		merged.MaxData = override.MaxData
+	}
+	if override.MaxBatch > 0 {
+		merged.MaxBatch = override.MaxBatch
+	}
+	if override.Action != "" {
+		merged.Action = override.Action
+	}
+	if override.DeadLetterKey != "" {
+		merged.DeadLetterKey = override.DeadLetterKey
+	}
+	return &merged
+}
+
+// action returns what to do with messages over a limit
+func (c *sizeLimitConfig) action() string {
+	if c == nil || c.Action == "" {
+		return limitDrop
+	}
+	return c.Action
+}
+
+func (c *sizeLimitConfig) deadLetterKey() string {
+	if c == nil || c.DeadLetterKey == "" {
+		return defaultDeadLetterKey
+	}
+	return c.DeadLetterKey
+}
+
+// exceeded returns the name of the first message or data limit msg is over, or ""
+// if it is within them. rawSize is the size of the envelope it came from, not the
+// whole Scribe message. It is safe to call on a nil config which is unlimited.
+func (c *sizeLimitConfig) exceeded(msg *centrifugoBroadcastParams, rawSize int) string {
+	if c == nil {
+		return ""
+	}
+	if c.MaxMessage > 0 && rawSize > c.MaxMessage {
+		return limitMessage
+	}
+	if c.MaxData > 0 && len(msg.Data) > c.MaxData {
+		return limitData
+	}
+	return ""
+}
+
+// envelopeOverLimit returns the limits, and the namespace they are for, that an
+// envelope of size bytes is over max_message_bytes of in every namespace it is
+// published to, or nil if it is within the limit for any of them. It is checked
+// as soon as the envelope is decoded so oversized ones are dropped before being
+// processed. Limits that truncate are left for after processing, when the data
+// to replace is known.
+func (c *Config) envelopeOverLimit(msg *centrifugoBroadcastParams, size int) (*sizeLimitConfig, string) {
+	var over *sizeLimitConfig
+	var overNamespace string
+	for _, ch := range msg.Channels {
+		namespace := channelNamespace(ch)
+		limits := c.limitsFor(namespace)
+		if limits == nil || limits.MaxMessage < 1 || size <= limits.MaxMessage || limits.action() == limitTruncate {
+			return nil, ""
+		}
+		if over == nil {
+			over, overNamespace = limits, namespace
+		}
+	}
+	return over, overNamespace
+}
+
+// truncatedRef replaces the data of an oversized message so clients know to
+// fetch it elsewhere. Ref is the message's hub format mid if it has one or else
+// a hash of the original, as used for de-duplication.
+type truncatedRef struct {
+	Truncated bool   `json:"truncated"`
+	Ref       string `json:"ref"`
+	Size      int    `json:"size"`
+}
+
+// truncateToRef returns reference data for msg
+func truncateToRef(msg *centrifugoBroadcastParams) (json.RawMessage, error) {
+	return json.Marshal(&truncatedRef{
+		Truncated: true,
+		Ref:       dedupKey(msg),
+		Size:      len(msg.Data),
+	})
+}
+
+// deadLetter is pushed as JSON to the dead letter list for a message over a size
+// limit so it can be inspected or replayed.
+type deadLetter struct {
+	key       string
+	Limit     string                    `json:"limit"`
+	Category  string                    `json:"category"`
+	Namespace string                    `json:"namespace"`
+	Size      int                       `json:"size"`
+	Params    centrifugoBroadcastParams `json:"params"`
+}
+
+// batchSizes tracks how much data a batch has published in total and per namespace
+type batchSizes struct {
+	total       int
+	byNamespace map[string]int
+}
+
+// fits reports whether size more bytes of namespace's data can be added to the
+// batch without going over the global or namespace batch limits.
+func (b *batchSizes) fits(size int, namespace string, global, ns *sizeLimitConfig) bool {
+	if global != nil && global.MaxBatch > 0 && b.total+size > global.MaxBatch {
+		return false
+	}
+	if ns != nil && ns.MaxBatch > 0 && b.byNamespace[namespace]+size > ns.MaxBatch {
+		return false
+	}
+	return true
+}
+
+func (b *batchSizes) add(size int, namespace string) {
+	if b.byNamespace == nil {
+		b.byNamespace = make(map[string]int)
+	}
+	b.total += size
+	b.byNamespace[namespace] += size
+}
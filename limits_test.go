@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestSizeLimits(t *testing.T) {
+	type testCase struct {
+		name              string
+		config            *Config
+		input             []string
+		expectData        []string
+		expectDeadLetters []string
+	}
+
+	small := "{\"channels\":[\"a:1\"], \"data\":{\"x\":1}}"
+	text := strings.Repeat("0123456789", 6)
+	bigData := "{\"mid\":\"m1\",\"text\":\"" + text + "\"}"
+	otherData := "{\"mid\":\"m2\",\"text\":\"" + text + "\"}"
+	big := "{\"channels\":[\"a:2\"], \"data\":" + bigData + "}"
+	other := "{\"channels\":[\"b:1\"], \"data\":" + otherData + "}"
+	ref := "{\"truncated\":true,\"ref\":\"mid:m1\",\"size\":82}"
+
+	tests := []testCase{
+		{
+			name:       "No limits",
+			input:      []string{small, big},
+			expectData: []string{"{\"x\":1}", bigData},
+		},
+		{
+			name:       "Data limit drops",
+			config:     &Config{Limits: &sizeLimitConfig{MaxData: 20}},
+			input:      []string{small, big},
+			expectData: []string{"{\"x\":1}"},
+		},
+		{
+			name:       "Message limit truncates",
+			config:     &Config{Limits: &sizeLimitConfig{MaxMessage: 50, Action: limitTruncate}},
+			input:      []string{small, big},
+			expectData: []string{"{\"x\":1}", ref},
+		},
+		{
+			name: "Namespace overrides global limit",
+			config: &Config{
+				Limits: &sizeLimitConfig{MaxData: 20},
+				Namespaces: map[string]*routeConfig{
+					"b": {Limits: &sizeLimitConfig{MaxData: 100}},
+				},
+			},
+			input:      []string{big, other},
+			expectData: []string{otherData},
+		},
+		{
+			name:              "Data limit dead letters",
+			config:            &Config{Limits: &sizeLimitConfig{MaxData: 20, Action: limitDeadLetter}},
+			input:             []string{small, big},
+			expectData:        []string{"{\"x\":1}"},
+			expectDeadLetters: []string{limitData},
+		},
+		{
+			name:              "Global batch limit",
+			config:            &Config{Limits: &sizeLimitConfig{MaxBatch: 100, Action: limitDeadLetter}},
+			input:             []string{big, small, other},
+			expectData:        []string{bigData, "{\"x\":1}"},
+			expectDeadLetters: []string{limitBatch},
+		},
+		{
+			name: "Namespace batch limit",
+			config: &Config{Namespaces: map[string]*routeConfig{
+				"a": {Limits: &sizeLimitConfig{MaxBatch: 60, Action: limitTruncate}},
+			}},
+			input:      []string{small, big, other},
+			expectData: []string{"{\"x\":1}", ref, otherData},
+		},
+	}
+
+	for _, test := range tests {
+		var entries []*scribe.LogEntry
+		for _, m := range test.input {
+			entries = append(entries, &scribe.LogEntry{Category: "c", Message: m})
+		}
+		batch := &batchContext{config: test.config}
+		out, _, err := scribeEntriesToBroadcastCommand(entries, batch, &statsd.NoopClient{})
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		var data []string
+		for _, cmd := range out.Data {
+			data = append(data, string(cmd.Params.Data))
+		}
+		if !reflect.DeepEqual(test.expectData, data) {
+			t.Errorf("Failed case %s: expected data %v got %v", test.name, test.expectData, data)
+		}
+		var deadLetters []string
+		for _, l := range batch.deadLetters {
+			deadLetters = append(deadLetters, l.Limit)
+			if l.key != defaultDeadLetterKey {
+				t.Errorf("Failed case %s: expected dead letter key %s got %s", test.name, defaultDeadLetterKey, l.key)
+			}
+			if _, err := json.Marshal(l); err != nil {
+				t.Errorf("Failed case %s: failed to encode dead letter %v", test.name, err)
+			}
+		}
+		if !reflect.DeepEqual(test.expectDeadLetters, deadLetters) {
+			t.Errorf("Failed case %s: expected dead letters %v got %v", test.name, test.expectDeadLetters, deadLetters)
+		}
+	}
+}
+
+func TestEnvelopeSizes(t *testing.T) {
+	ndjson := "{\"channels\":[\"a\"], \"data\":{}}\n{\"channels\":[\"b\"], \"data\":{\"x\":1}}"
+	first := []byte{0x81, 0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0x91, 0xa1, 'a'}
+	second := []byte{0x82, 0xa8, 'c', 'h', 'a', 'n', 'n', 'e', 'l', 's', 0x91, 0xa1, 'b', 0xa4, 'd', 'a', 't', 'a', 0xa2, '{', '}'}
+	mpList := append(append([]byte{0x92}, first...), second...)
+
+	type testCase struct {
+		name     string
+		input    []byte
+		encoding string
+		expect   []int
+	}
+	tests := []testCase{
+		{"NDJSON", []byte(ndjson), encodingJSON, []int{len("{\"channels\":[\"a\"], \"data\":{}}"), len("{\"channels\":[\"b\"], \"data\":{\"x\":1}}")}},
+		{"Msgpack array", mpList, encodingMsgpack, []int{len(first), len(second)}},
+	}
+	for _, test := range tests {
+		var sizes []int
+		for _, d := range decodeEnvelopes(test.input, test.encoding) {
+			sizes = append(sizes, len(d.raw))
+		}
+		if !reflect.DeepEqual(test.expect, sizes) {
+			t.Errorf("Failed case %s: expected envelope sizes %v got %v", test.name, test.expect, sizes)
+		}
+	}
+}
+
+func TestMessageLimitPerEnvelope(t *testing.T) {
+	small := "{\"channels\":[\"a:1\"], \"data\":{\"mid\":\"s\"}}"
+	big := "{\"channels\":[\"a:2\"], \"data\":{\"mid\":\"b\",\"text\":\"" + strings.Repeat("x", 100) + "\"}}"
+	entries := []*scribe.LogEntry{
+		// Several small envelopes in one message are each within the limit
+		{Category: "c", Message: small + "\n" + small + "\n" + small},
+		{Category: "c", Message: big},
+	}
+	batch := &batchContext{
+		config: &Config{Limits: &sizeLimitConfig{MaxMessage: 60}},
+		dedup:  newLocalDedupStore(time.Minute, 100),
+	}
+	out, _, err := scribeEntriesToBroadcastCommand(entries, batch, &statsd.NoopClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 1 {
+		t.Errorf("Expected one small envelope published and the rest duplicates, got %d commands", len(out.Data))
+	}
+	// The oversized envelope is dropped before de-duplication and later stages
+	if !reflect.DeepEqual(batch.dedupKeys, []string{"mid:s"}) {
+		t.Errorf("Expected only the small envelope to be recorded for dedup, got %v", batch.dedupKeys)
+	}
+	if batch.entries[1].reason != "too_large."+limitMessage {
+		t.Errorf("Expected big envelope dropped as too large, got %q", batch.entries[1].reason)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// connLimits restricts which clients may connect and how many connections
+// they may hold open. Zero limits are unlimited.
+type connLimits struct {
+	allow, deny []*net.IPNet
+	maxConns    int
+	maxPerIP    int
+}
+
+// parseCIDRs parses a comma separated list of CIDR blocks. Plain IPs are
+// treated as a block of one address.
+func parseCIDRs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR %q: %s", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// permitted reports whether ip may connect at all. Deny takes precedence over
+// allow, and if there is an allow list ip must be in it.
+func (l *connLimits) permitted(ip net.IP) bool {
+	if containsIP(l.deny, ip) {
+		return false
+	}
+	return len(l.allow) < 1 || containsIP(l.allow, ip)
+}
+
+// limitedServerTransport wraps a server transport to reject connections that
+// aren't permitted or would go over the connection limits. Rejected connections
+// are closed straight away and Accept waits for the next one.
+type limitedServerTransport struct {
+	thrift.TServerTransport
+	limits *connLimits
+	sd     statsd.Statsd
+	// gauge is the metric the number of open connections is recorded in
+	gauge string
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+func newLimitedServerTransport(inner thrift.TServerTransport, limits *connLimits, sd statsd.Statsd) *limitedServerTransport {
+	return &limitedServerTransport{
+		TServerTransport: inner,
+		limits:           limits,
+		sd:               sd,
+		gauge:            "connections.open",
+		perIP:            make(map[string]int),
+	}
+}
+
+func (t *limitedServerTransport) Accept() (thrift.TTransport, error) {
+	for {
+		client, err := t.TServerTransport.Accept()
+		if err != nil || client == nil {
+			return client, err
+		}
+		if conn := t.admit(client); conn != nil {
+			return conn, nil
+		}
+		client.Close()
+	}
+}
+
+// admit returns client wrapped to release its connection slot when closed, or
+// nil if it should be rejected
+func (t *limitedServerTransport) admit(client thrift.TTransport) *limitedConn {
+	var key string
+	if ip := remoteIP(client); ip != nil {
+		key = ip.String()
+		if !t.limits.permitted(ip) {
+			glog.Warningf("Rejecting connection from %s: not permitted by CIDR lists", key)
+			t.sd.Incr("connections.rejected.denied", 1)
+			return nil
+		}
+	} else if isUnixConn(client) {
+		// CIDR lists don't apply to local clients, but they share the per IP
+		// limit as if they had all connected over loopback
+		key = unixPeer
+	} else {
+		// Can't check it so let it through uncounted rather than break the listener
+		glog.Warningf("Can't get remote address of %T, skipping connection limits", client)
+		return &limitedConn{TTransport: client, owner: t}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.limits.maxConns > 0 && t.total >= t.limits.maxConns {
+		glog.Warningf("Rejecting connection from %s: %d connections open", key, t.total)
+		t.sd.Incr("connections.rejected.max_conns", 1)
+		return nil
+	}
+	if t.limits.maxPerIP > 0 && t.perIP[key] >= t.limits.maxPerIP {
+		glog.Warningf("Rejecting connection from %s: %d connections open from it", key, t.perIP[key])
+		t.sd.Incr("connections.rejected.max_conns_per_ip", 1)
+		return nil
+	}
+	t.total++
+	t.perIP[key]++
+	t.sd.Gauge(t.gauge, int64(t.total))
+	return &limitedConn{TTransport: client, owner: t, ip: key, counted: true}
+}
+
+func (t *limitedServerTransport) release(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total--
+	if t.perIP[ip]--; t.perIP[ip] < 1 {
+		delete(t.perIP, ip)
+	}
+	t.sd.Gauge(t.gauge, int64(t.total))
+}
+
+// Connection limits key for clients connected over a Unix domain socket
+const unixPeer = "unix"
+
+// clientConn returns the network connection of a client, or nil if unknown
+func clientConn(client thrift.TTransport) net.Conn {
+	withConn, ok := client.(interface {
+		Conn() net.Conn
+	})
+	if !ok {
+		return nil
+	}
+	return withConn.Conn()
+}
+
+// remoteIP returns the IP a client connected from, or nil if unknown
+func remoteIP(client thrift.TTransport) net.IP {
+	if conn := clientConn(client); conn != nil {
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			return addr.IP
+		}
+	}
+	return nil
+}
+
+// isUnixConn reports whether client connected over a Unix domain socket. The
+// local address is checked as clients' sockets usually don't have one.
+func isUnixConn(client thrift.TTransport) bool {
+	if conn := clientConn(client); conn != nil {
+		_, ok := conn.LocalAddr().(*net.UnixAddr)
+		return ok
+	}
+	return false
+}
+
+// limitedConn is an accepted connection that gives up its slot when closed
+type limitedConn struct {
+	thrift.TTransport
+	owner   *limitedServerTransport
+	ip      string
+	counted bool
+	once    sync.Once
+}
+
+func (c *limitedConn) Read(buf []byte) (int, error) {
+	n, err := c.TTransport.Read(buf)
+	if terr, ok := err.(thrift.TTransportException); ok && terr.TypeId() == thrift.TIMED_OUT {
+		c.owner.sd.Incr("connections.idle_timeout", 1)
+	}
+	return n, err
+}
+
+// Close may be called once each for the input and output transports so only
+// releases the slot the first time
+func (c *limitedConn) Close() error {
+	c.once.Do(func() {
+		if c.counted {
+			c.owner.release(c.ip)
+		}
+	})
+	return c.TTransport.Close()
+}
+
+// unwrapTransport returns the transport a limitedConn wraps, or trans itself
+func unwrapTransport(trans thrift.TTransport) thrift.TTransport {
+	if c, ok := trans.(*limitedConn); ok {
+		return c.TTransport
+	}
+	return trans
+}
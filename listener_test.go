@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+func TestConnPermitted(t *testing.T) {
+	type testCase struct {
+		name   string
+		allow  string
+		deny   string
+		ip     string
+		expect bool
+	}
+
+	tests := []testCase{
+		{"No lists", "", "", "192.0.2.1", true},
+		{"In allow list", "10.0.0.0/8, 192.0.2.0/24", "", "192.0.2.1", true},
+		{"Not in allow list", "10.0.0.0/8", "", "192.0.2.1", false},
+		{"In deny list", "", "192.0.2.1", "192.0.2.1", false},
+		{"Deny overrides allow", "192.0.2.0/24", "192.0.2.128/25", "192.0.2.200", false},
+		{"IPv6", "2001:db8::/32", "", "2001:db8::1", true},
+	}
+
+	for _, test := range tests {
+		allow, err := parseCIDRs(test.allow)
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		deny, err := parseCIDRs(test.deny)
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		limits := &connLimits{allow: allow, deny: deny}
+		if got := limits.permitted(net.ParseIP(test.ip)); got != test.expect {
+			t.Errorf("Failed case %s: expected %v got %v", test.name, test.expect, got)
+		}
+	}
+
+	if _, err := parseCIDRs("10.0.0.0/33"); err == nil {
+		t.Errorf("Expected invalid CIDR error")
+	}
+}
+
+// addrConn is a connection from a given remote address
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func TestConnLimits(t *testing.T) {
+	lt := newLimitedServerTransport(nil, &connLimits{maxConns: 3, maxPerIP: 2}, &statsd.NoopClient{})
+	connect := func(ip string) *limitedConn {
+		server, client := net.Pipe()
+		client.Close()
+		conn := &addrConn{Conn: server, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}}
+		return lt.admit(thrift.NewTSocketFromConnTimeout(conn, 0))
+	}
+
+	a1, a2 := connect("192.0.2.1"), connect("192.0.2.1")
+	if a1 == nil || a2 == nil {
+		t.Fatalf("Expected first two connections to be admitted")
+	}
+	if connect("192.0.2.1") != nil {
+		t.Errorf("Expected third connection from one IP to be rejected")
+	}
+	b1 := connect("192.0.2.2")
+	if b1 == nil {
+		t.Fatalf("Expected connection from another IP to be admitted")
+	}
+	if connect("192.0.2.3") != nil {
+		t.Errorf("Expected connection over total limit to be rejected")
+	}
+
+	// Closing input and output transports releases only one slot
+	a1.Close()
+	a1.Close()
+	if lt.total != 2 || lt.perIP["192.0.2.1"] != 1 {
+		t.Errorf("Expected 2 connections with 1 from 192.0.2.1 got %d and %d", lt.total, lt.perIP["192.0.2.1"])
+	}
+	if connect("192.0.2.1") == nil {
+		t.Errorf("Expected connection to be admitted after one closed")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// listenerConfig is an extra Thrift listener served alongside -addr. Each has
+// its own transport, protocol, connection limits and auth, and can give rules
+// to categories the config has none for. Listeners are only started when the
+// process starts, but their default route and clients reload with the config.
+type listenerConfig struct {
+	// Name identifies the listener in logs and metrics
+	Name string `json:"name"`
+	// Addr is a host:port or unix:///path/to/socket
+	Addr string `json:"addr"`
+	// Transport and Protocol are as for -transport and -protocol, defaulting to
+	// framed binary
+	Transport string `json:"transport"`
+	Protocol  string `json:"protocol"`
+	// DefaultRoute is the rules for messages received on this listener in
+	// categories with no rules in categories
+	DefaultRoute *routeConfig `json:"default_route"`
+	// Clients replaces the top level clients for connections to this listener
+	Clients map[string][]string `json:"clients"`
+
+	TLSCert     string `json:"tls_cert"`
+	TLSKey      string `json:"tls_key"`
+	TLSClientCA string `json:"tls_client_ca"`
+	AllowCIDR   string `json:"allow_cidr"`
+	DenyCIDR    string `json:"deny_cidr"`
+	// MaxConns, MaxConnsPerIP and IdleTimeout (in seconds) are as for the
+	// flags of the same name. Zero is unlimited.
+	MaxConns      int `json:"max_conns"`
+	MaxConnsPerIP int `json:"max_conns_per_ip"`
+	IdleTimeout   int `json:"idle_timeout"`
+}
+
+func (l *listenerConfig) validate() error {
+	if l.Name == "" || strings.ContainsAny(l.Name, ". ") {
+		return fmt.Errorf("name %q must be set and not contain dots or spaces", l.Name)
+	}
+	if l.Addr == "" {
+		return fmt.Errorf("addr is required")
+	}
+	if _, _, err := newServerFactories(l.transport(), l.protocol(), &statsd.NoopClient{}); err != nil {
+		return err
+	}
+	if (l.TLSCert == "") != (l.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+	if l.TLSClientCA != "" && l.TLSCert == "" {
+		return fmt.Errorf("tls_client_ca requires tls_cert")
+	}
+	if _, err := l.connLimits(); err != nil {
+		return err
+	}
+	return l.DefaultRoute.validate()
+}
+
+func (l *listenerConfig) transport() string {
+	if l.Transport == "" {
+		return transportFramed
+	}
+	return l.Transport
+}
+
+func (l *listenerConfig) protocol() string {
+	if l.Protocol == "" {
+		return protocolBinary
+	}
+	return l.Protocol
+}
+
+func (l *listenerConfig) connLimits() (*connLimits, error) {
+	limits := &connLimits{maxConns: l.MaxConns, maxPerIP: l.MaxConnsPerIP}
+	var err error
+	if limits.allow, err = parseCIDRs(l.AllowCIDR); err != nil {
+		return nil, err
+	}
+	if limits.deny, err = parseCIDRs(l.DenyCIDR); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// serverOptions returns how to serve the listener
+func (l *listenerConfig) serverOptions(socketMode os.FileMode) (*serverOptions, error) {
+	limits, err := l.connLimits()
+	if err != nil {
+		return nil, err
+	}
+	return &serverOptions{
+		name:        l.Name,
+		addr:        l.Addr,
+		transport:   l.transport(),
+		protocol:    l.protocol(),
+		tlsCert:     l.TLSCert,
+		tlsKey:      l.TLSKey,
+		tlsClientCA: l.TLSClientCA,
+		limits:      limits,
+		idleTimeout: time.Duration(l.IdleTimeout) * time.Second,
+		socketMode:  socketMode,
+	}, nil
+}
+
+// listener returns the named listener's config, or nil if there is none.
+// It is safe to call on a nil Config.
+func (c *Config) listener(name string) *listenerConfig {
+	if c == nil {
+		return nil
+	}
+	for _, l := range c.Listeners {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// forListener returns the config for messages received on a listener, with its
+// default route and clients in place of the top level ones. started is the
+// listener's config when it was started, or nil for -addr which uses c as is.
+// If a reload removed or renamed the listener it keeps the rules it started
+// with, so it never falls back to the less strict top level ones.
+func (c *Config) forListener(started *listenerConfig) *Config {
+	if c == nil || started == nil {
+		return c
+	}
+	l := c.listener(started.Name)
+	if l == nil {
+		l = started
+	}
+	if l.DefaultRoute == nil && l.Clients == nil {
+		return c
+	}
+	derived := *c
+	derived.defaultRoute = l.DefaultRoute
+	if l.Clients != nil {
+		derived.Clients = l.Clients
+	}
+	return &derived
+}
+
+// serverOptions is how to serve Scribe on one listener
+type serverOptions struct {
+	// name is the listener's name in the config, or "" for -addr
+	name                         string
+	addr, transport, protocol    string
+	tlsCert, tlsKey, tlsClientCA string
+	limits                       *connLimits
+	idleTimeout                  time.Duration
+	socketMode                   os.FileMode
+}
+
+// newThriftServer listens as o describes, serving processors from
+// processorFactory
+func newThriftServer(o *serverOptions, processorFactory thrift.TProcessorFactory, sd statsd.Statsd) (*thrift.TSimpleServer, error) {
+	transportFactory, protocolFactory, err := newServerFactories(o.transport, o.protocol, sd)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs *tlsCerts
+	if len(o.tlsCert) > 0 {
+		if certs, err = newTLSCerts(o.tlsCert, o.tlsKey, o.tlsClientCA); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := listen(o.addr, o.socketMode)
+	if err != nil {
+		return nil, err
+	}
+	var transport *listenerServerTransport
+	if certs != nil {
+		certs.watch()
+		transport = newListenerServerTransport(listener, certs.config(), o.idleTimeout)
+	} else {
+		transport = newListenerServerTransport(listener, nil, o.idleTimeout)
+	}
+
+	limited := newLimitedServerTransport(transport, o.limits, sd)
+	if o.name != "" {
+		limited.gauge = "listeners." + o.name + ".connections.open"
+	}
+	return thrift.NewTSimpleServerFactory4(processorFactory, limited, transportFactory, protocolFactory), nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestListenerConfigValidate(t *testing.T) {
+	type testCase struct {
+		name     string
+		listener listenerConfig
+		ok       bool
+	}
+
+	tests := []testCase{
+		{"minimal", listenerConfig{Name: "legacy", Addr: ":1464"}, true},
+		{"full", listenerConfig{Name: "legacy", Addr: "unix:///tmp/s.sock", Transport: "buffered", Protocol: "compact",
+			TLSCert: "cert.pem", TLSKey: "key.pem", TLSClientCA: "ca.pem", AllowCIDR: "10.0.0.0/8", MaxConns: 10,
+			DefaultRoute: &routeConfig{Encoding: encodingMsgpack}}, true},
+		{"no name", listenerConfig{Addr: ":1464"}, false},
+		{"dotted name", listenerConfig{Name: "a.b", Addr: ":1464"}, false},
+		{"no addr", listenerConfig{Name: "legacy"}, false},
+		{"bad transport", listenerConfig{Name: "legacy", Addr: ":1464", Transport: "http"}, false},
+		{"bad protocol", listenerConfig{Name: "legacy", Addr: ":1464", Protocol: "xml"}, false},
+		{"key without cert", listenerConfig{Name: "legacy", Addr: ":1464", TLSKey: "key.pem"}, false},
+		{"ca without cert", listenerConfig{Name: "legacy", Addr: ":1464", TLSClientCA: "ca.pem"}, false},
+		{"bad cidr", listenerConfig{Name: "legacy", Addr: ":1464", DenyCIDR: "10.0.0.0/99"}, false},
+		{"bad route", listenerConfig{Name: "legacy", Addr: ":1464", DefaultRoute: &routeConfig{Encoding: "xml"}}, false},
+	}
+
+	for _, test := range tests {
+		err := test.listener.validate()
+		if (err == nil) != test.ok {
+			t.Errorf("Failed case %s: expected ok %v got err %v", test.name, test.ok, err)
+		}
+	}
+
+	dup := &Config{Listeners: []*listenerConfig{{Name: "a", Addr: ":1"}, {Name: "a", Addr: ":2"}}}
+	if err := dup.validate(); err == nil {
+		t.Errorf("Expected error for duplicate listener names")
+	}
+}
+
+func TestConfigForListener(t *testing.T) {
+	legacyRoute := &routeConfig{Encoding: encodingMsgpack}
+	cfg := &Config{
+		Categories: map[string]*routeConfig{"hub": {Encoding: encodingProtobuf}},
+		Clients:    map[string][]string{"app": {"hub"}},
+		Listeners: []*listenerConfig{
+			{Name: "legacy", Addr: ":1464", DefaultRoute: legacyRoute, Clients: map[string][]string{"old": {"*"}}},
+			{Name: "plain", Addr: ":1465"},
+		},
+	}
+
+	if got := cfg.forListener(nil); got != cfg {
+		t.Errorf("Expected -addr listener to use config as is")
+	}
+	if got := cfg.forListener(cfg.Listeners[1]); got != cfg {
+		t.Errorf("Expected listener without overrides to use config as is")
+	}
+	var nilConfig *Config
+	if got := nilConfig.forListener(cfg.Listeners[0]); got != nil {
+		t.Errorf("Expected nil config to stay nil")
+	}
+
+	legacy := cfg.forListener(cfg.Listeners[0])
+	if got := legacy.encodingFor("other"); got != encodingMsgpack {
+		t.Errorf("Expected default route encoding for unknown category got %q", got)
+	}
+	if got := legacy.encodingFor("hub"); got != encodingProtobuf {
+		t.Errorf("Expected category encoding to override default route got %q", got)
+	}
+	if !legacy.clientAllowed([]string{"old"}, "anything") || legacy.clientAllowed([]string{"app"}, "hub") {
+		t.Errorf("Expected listener clients to replace top level ones")
+	}
+	if got := cfg.encodingFor("other"); got != "" {
+		t.Errorf("Expected shared config to be unchanged got %q", got)
+	}
+}
+
+func TestListenerRemovedOnReload(t *testing.T) {
+	started := &Config{
+		Listeners: []*listenerConfig{
+			{Name: "legacy", Addr: ":1464", Clients: map[string][]string{"old": {"hub"}}},
+		},
+	}
+	handler := &Handler{}
+	handler.SetConfig(started)
+	client := &clientHandler{Handler: handler, listener: started.Listeners[0], subjects: []string{"other"}}
+	entries := []*scribe.LogEntry{{Category: "hub", Message: "{}"}}
+
+	// The listener is still running after a reload drops it from the config, and
+	// must keep its own clients rather than allowing everyone
+	for _, reloaded := range []*Config{
+		{},
+		{Listeners: []*listenerConfig{{Name: "renamed", Addr: ":1464"}}},
+	} {
+		handler.SetConfig(reloaded)
+		cfg := client.currentConfig().forListener(client.listener)
+		if got := allowedEntries(cfg, client.subjects, entries, &statsd.NoopClient{}); len(got) != 0 {
+			t.Errorf("Expected client to be refused after reload to %+v got %d entries", reloaded, len(got))
+		}
+		if !cfg.clientAllowed([]string{"old"}, "hub") {
+			t.Errorf("Expected listener's startup clients to still be allowed after reload to %+v", reloaded)
+		}
+	}
+}
+
+func TestMultipleListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	handler := &countingScribe{received: make(chan string, 1)}
+	processorFactory := thrift.NewTProcessorFactory(scribe.NewScribeProcessor(handler))
+
+	listeners := []*listenerConfig{
+		{Name: "scribe", Addr: unixScheme + filepath.Join(dir, "scribe.sock")},
+		{Name: "legacy", Addr: unixScheme + filepath.Join(dir, "legacy.sock"), Transport: transportBuffered, Protocol: protocolCompact},
+	}
+	for _, l := range listeners {
+		if err := l.validate(); err != nil {
+			t.Fatal(err)
+		}
+		opts, err := l.serverOptions(0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := newThriftServer(opts, processorFactory, &statsd.NoopClient{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go server.Serve()
+		defer server.Stop()
+	}
+
+	for _, l := range listeners {
+		path, _ := unixSocketPath(l.Addr)
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		socket := thrift.NewTSocketFromConnTimeout(conn, 5*time.Second)
+		var trans thrift.TTransport = thrift.NewTFramedTransport(socket)
+		if l.transport() == transportBuffered {
+			trans = thrift.NewTBufferedTransport(socket, transportBufferSize)
+		}
+		client := scribe.NewScribeClientFactory(trans, protocolFactories[l.protocol()])
+		result, err := client.Log([]*scribe.LogEntry{{Category: "test", Message: l.Name}})
+		if err != nil || result != scribe.ResultCode_OK {
+			t.Errorf("Failed listener %s: expected OK got %v, err %v", l.Name, result, err)
+		} else if got := <-handler.received; got != l.Name {
+			t.Errorf("Failed listener %s: server received %q", l.Name, got)
+		}
+		conn.Close()
+	}
+}
@@ -3,20 +3,36 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
 	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
-	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
 
 	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
 )
 
 func main() {
-	var addr, redisAddr, apiKey, statsdHost, statsdPrefix string
-	var redisDB, redisIdleTimeout, numPubShards int
+	var addr, redisAddr, apiKey, statsdHost, statsdPrefix, configPath string
+	var scriptPath, dedupKeyPrefix, keyringPath string
+	var tlsCert, tlsKey, tlsClientCA, allowCIDRs, denyCIDRs string
+	var transportName, protocolName, thriftHTTPAddr, thriftHTTPTokens string
+	var publishAddr, publishTokens, socketModeName string
+	var udpAddr, udpCategory string
+	var udpBatchSize, udpBatchWait, udpQueueSize, udpReadBuffer int
+	var maxConns, maxConnsPerIP, idleTimeout int
+	var redisDB, redisIdleTimeout, numPubShards, scriptTimeout, scriptMaxRegistry, scriptMaxMemory int
+	var dedupWindow, dedupMaxEntries int
+	var maxDecompressedSize int64
+	var dedupShared, fb303Shutdown bool
 
 	flag.StringVar(&addr, "addr", "0.0.0.0:1463",
-		"The host:port to listen on")
+		"The host:port or unix:///path/to/socket to listen on")
 	flag.StringVar(&redisAddr, "redis",
 		"localhost:6379", "The host:port to talk to redis on")
 	flag.IntVar(&redisDB, "redis-db", 0,
@@ -33,6 +49,75 @@ func main() {
 		"hostname:port for statsd. If none given then metrics are not recorded")
 	flag.StringVar(&statsdPrefix, "statsd-prefix", "centrifugo-scriber.",
 		"Prefix for statsd metrics logged")
+	flag.StringVar(&configPath, "config", "",
+		"Path to a JSON file of per-category and per-namespace processing rules. "+
+			"Send SIGHUP to reload it")
+	flag.StringVar(&scriptPath, "script", "",
+		"Path to a Lua script defining a process(msg) function to run on every message. "+
+			"The file is reloaded when it changes")
+	flag.IntVar(&scriptTimeout, "script-timeout", 50,
+		"How many milliseconds the script may run for on each message before the message is dropped")
+	flag.IntVar(&scriptMaxRegistry, "script-max-registry", 65536,
+		"Maximum size of the script's Lua value stack, which limits how deeply it can recurse")
+	flag.IntVar(&scriptMaxMemory, "script-max-memory", 64,
+		"About how many megabytes the script may allocate on each message before the message is dropped")
+	flag.IntVar(&dedupWindow, "dedup-window", 0,
+		"How many seconds to remember message ids for to drop duplicate deliveries. "+
+			"Messages are identified by mid or else a hash of channels and data. Default is 0 which disables de-duplication")
+	flag.IntVar(&dedupMaxEntries, "dedup-max-entries", 100000,
+		"Maximum number of message ids to remember in the local de-duplication cache. Must be positive")
+	flag.BoolVar(&dedupShared, "dedup-redis", false,
+		"Remember message ids in redis instead of locally so duplicates are dropped across all scriber instances")
+	flag.StringVar(&dedupKeyPrefix, "dedup-redis-key-pfx", "centrifugo-scriber.dedup.",
+		"Redis key prefix for message ids when using -dedup-redis")
+	flag.StringVar(&keyringPath, "keyring", "",
+		"Path to a JSON file of keys to verify signed messages with. Send SIGHUP to reload it")
+	flag.Int64Var(&maxDecompressedSize, "max-decompressed-size", defaultMaxDecompressedSize,
+		"Maximum size in bytes a gzip or zstd compressed message may decompress to. Larger messages are dropped")
+	flag.StringVar(&tlsCert, "tls-cert", "",
+		"Path to a PEM certificate to serve TLS with. Requires -tls-key. Send SIGHUP to reload it")
+	flag.StringVar(&tlsKey, "tls-key", "",
+		"Path to the PEM private key for -tls-cert")
+	flag.StringVar(&tlsClientCA, "tls-client-ca", "",
+		"Path to a PEM bundle of CAs to verify client certificates against. If set clients must present a certificate")
+	flag.StringVar(&allowCIDRs, "allow-cidr", "",
+		"Comma separated CIDR blocks clients may connect from. Default is to allow all")
+	flag.StringVar(&denyCIDRs, "deny-cidr", "",
+		"Comma separated CIDR blocks clients may not connect from. Takes precedence over -allow-cidr")
+	flag.IntVar(&maxConns, "max-conns", 0,
+		"Maximum number of concurrent client connections. Default is 0 which means unlimited")
+	flag.IntVar(&maxConnsPerIP, "max-conns-per-ip", 0,
+		"Maximum number of concurrent client connections from each IP. Default is 0 which means unlimited")
+	flag.IntVar(&idleTimeout, "idle-timeout", 0,
+		"How many seconds a client connection can be idle before it is closed. Default is 0 which means never")
+	flag.StringVar(&transportName, "transport", transportFramed,
+		"Thrift transport to serve: framed, buffered, zlib or auto to detect it for each connection")
+	flag.StringVar(&protocolName, "protocol", protocolBinary,
+		"Thrift protocol to serve: binary, compact, json or auto to detect it for each connection")
+	flag.StringVar(&thriftHTTPAddr, "thrift-http-addr", "",
+		"The host:port or unix:///path/to/socket to serve Scribe Thrift over HTTP POST on. If none given HTTP is not served")
+	flag.StringVar(&thriftHTTPTokens, "thrift-http-tokens", "",
+		"Comma separated tokens HTTP clients must send one of in an \"Authorization: Bearer <token>\" header. Default is no auth")
+	flag.StringVar(&publishAddr, "http-publish-addr", "",
+		"The host:port or unix:///path/to/socket to serve the JSON publish API on at /publish. If none given it is not served")
+	flag.StringVar(&publishTokens, "http-publish-tokens", "",
+		"Comma separated tokens publish API clients must send one of in an \"Authorization: Bearer <token>\" header. Default is no auth")
+	flag.BoolVar(&fb303Shutdown, "fb303-shutdown", false,
+		"Allow fb303 shutdown calls to stop the server. Default is to ignore them")
+	flag.StringVar(&socketModeName, "socket-mode", defaultSocketMode,
+		"Octal permissions of Unix domain socket files listened on. Stale socket files are replaced on startup")
+	flag.StringVar(&udpAddr, "udp-addr", "",
+		"The host:port to receive UDP datagrams of messages on. If none given UDP is not served")
+	flag.StringVar(&udpCategory, "udp-category", "udp",
+		"Category whose rules apply to messages received over UDP")
+	flag.IntVar(&udpBatchSize, "udp-batch-size", 100,
+		"Maximum number of UDP datagrams to publish in one batch")
+	flag.IntVar(&udpBatchWait, "udp-batch-wait", 10,
+		"How many milliseconds to wait for a batch of UDP datagrams to fill before publishing it")
+	flag.IntVar(&udpQueueSize, "udp-queue-size", 10000,
+		"How many UDP datagrams can wait to be published. Datagrams received when it is full are dropped")
+	flag.IntVar(&udpReadBuffer, "udp-read-buffer", 0,
+		"Size in bytes of the UDP socket receive buffer. Default is 0 which means the OS default")
 	flag.Parse()
 
 	var statsdClient *statsd.StatsdClient
@@ -45,29 +130,193 @@ func main() {
 	} else {
 		sd = &statsd.NoopClient{}
 	}
+	// Keep totals of counters for fb303 getCounters
+	counters := newStatsCounters(sd)
+	sd = counters
 
-	var transport thrift.TServerTransport
-	var err error
+	socketMode, err := parseSocketMode(socketModeName)
+	if err != nil {
+		panic(err)
+	}
 
-	protocolFactory := thrift.NewTBinaryProtocolFactoryDefault()
-	transportFactory := thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())
+	limits := &connLimits{maxConns: maxConns, maxPerIP: maxConnsPerIP}
+	if limits.allow, err = parseCIDRs(allowCIDRs); err != nil {
+		panic(err)
+	}
+	if limits.deny, err = parseCIDRs(denyCIDRs); err != nil {
+		panic(err)
+	}
 
-	transport, err = thrift.NewTServerSocket(addr)
+	handler, err := NewHandler(redisAddr, redisDB, redisIdleTimeout, numPubShards, apiKey, sd)
 	if err != nil {
 		panic(err)
 	}
+	fb := newFb303Handler(counters, handler.redisHealth)
 
-	handler, err := NewHandler(redisAddr, redisDB, redisIdleTimeout, numPubShards, apiKey, sd)
+	server, err := newThriftServer(&serverOptions{
+		addr:        addr,
+		transport:   transportName,
+		protocol:    protocolName,
+		tlsCert:     tlsCert,
+		tlsKey:      tlsKey,
+		tlsClientCA: tlsClientCA,
+		limits:      limits,
+		idleTimeout: time.Duration(idleTimeout) * time.Second,
+		socketMode:  socketMode,
+	}, &clientProcessorFactory{handler: handler, fb303: fb}, sd)
 	if err != nil {
 		panic(err)
 	}
 
-	processor := scribe.NewScribeProcessor(handler)
-	server := thrift.NewTSimpleServer4(processor, transport, transportFactory, protocolFactory)
+	if err := handler.SetMaxDecompressedSize(maxDecompressedSize); err != nil {
+		panic(err)
+	}
+
+	if dedupWindow > 0 {
+		if err := handler.EnableDedup(time.Duration(dedupWindow)*time.Second, dedupMaxEntries, dedupShared, dedupKeyPrefix); err != nil {
+			panic(err)
+		}
+	}
+
+	var cfg *Config
+	if len(configPath) > 0 {
+		if cfg, err = LoadConfig(configPath); err != nil {
+			panic(err)
+		}
+		handler.SetConfig(cfg)
+		watchConfig(configPath, handler)
+	}
+
+	if len(keyringPath) > 0 {
+		keyring, err := LoadKeyring(keyringPath)
+		if err != nil {
+			panic(err)
+		}
+		handler.SetKeyring(keyring)
+		watchKeyring(keyringPath, handler)
+	}
+
+	if len(scriptPath) > 0 {
+		script, err := NewScriptEngine(scriptPath, time.Duration(scriptTimeout)*time.Millisecond,
+			scriptMaxRegistry, int64(scriptMaxMemory)<<20, sd)
+		if err != nil {
+			panic(err)
+		}
+		script.Watch()
+		handler.SetScript(script)
+	}
+
+	// Clients over HTTP and UDP have no certificate, but are still subject to the
+	// client rules
+	anonymous := &clientHandler{Handler: handler}
+
+	if len(thriftHTTPAddr) > 0 {
+		httpHandler := &thriftHTTPHandler{
+			processor: newScribeProcessor(anonymous, fb),
+			protocol:  protocolName,
+			tokens:    parseTokens(thriftHTTPTokens),
+			sd:        sd,
+		}
+		l, err := listen(thriftHTTPAddr, socketMode)
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			fmt.Println("Starting the Thrift HTTP server... on ", thriftHTTPAddr)
+			panic(http.Serve(l, httpHandler))
+		}()
+	}
+
+	if len(publishAddr) > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/publish", &publishHTTPHandler{
+			handler: anonymous,
+			tokens:  parseTokens(publishTokens),
+			sd:      sd,
+		})
+		l, err := listen(publishAddr, socketMode)
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			fmt.Println("Starting the publish API server... on ", publishAddr)
+			panic(http.Serve(l, mux))
+		}()
+	}
+
+	if len(udpAddr) > 0 {
+		conn, err := net.ListenPacket("udp", udpAddr)
+		if err != nil {
+			panic(err)
+		}
+		if udpReadBuffer > 0 {
+			if err := conn.(*net.UDPConn).SetReadBuffer(udpReadBuffer); err != nil {
+				panic(err)
+			}
+		}
+		fmt.Println("Receiving UDP datagrams... on ", udpAddr)
+		newUDPListener(conn, anonymous, udpCategory, udpBatchSize,
+			time.Duration(udpBatchWait)*time.Millisecond, udpQueueSize, sd).run()
+	}
+
+	servers := []*thrift.TSimpleServer{server}
+	reloadable := len(configPath) > 0 || len(keyringPath) > 0 || len(tlsCert) > 0
+	if cfg != nil {
+		for _, l := range cfg.Listeners {
+			opts, err := l.serverOptions(socketMode)
+			if err != nil {
+				panic(err)
+			}
+			listenerServer, err := newThriftServer(opts,
+				&clientProcessorFactory{handler: handler, listener: l, fb303: fb}, sd)
+			if err != nil {
+				panic(err)
+			}
+			servers = append(servers, listenerServer)
+			reloadable = reloadable || len(l.TLSCert) > 0
+			go func(l *listenerConfig) {
+				fmt.Printf("Starting listener %s... on %s\n", l.Name, l.Addr)
+				if err := listenerServer.Serve(); err != nil {
+					panic(err)
+				}
+			}(l)
+		}
+	}
+
+	if reloadable {
+		fb.setReinitialize(sighupReinitialize)
+	}
+	var stopOnce sync.Once
+	stopServers := func() {
+		stopOnce.Do(func() {
+			for _, s := range servers {
+				s.Stop()
+			}
+		})
+	}
+	if fb303Shutdown {
+		fb.setShutdown(stopServers)
+	}
+	watchStopSignals(stopServers)
 
 	fmt.Println("Starting the simple server... on ", addr)
 	err = server.Serve()
 	if err != nil {
 		panic(err)
 	}
+	// Stopped by SIGTERM, SIGINT or fb303 shutdown
+	handler.FlushDebounced()
+	glog.Flush()
+}
+
+// watchStopSignals calls stop when the process receives SIGTERM or SIGINT, so
+// main can publish debounced messages before exiting.
+func watchStopSignals(stop func()) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		s := <-sig
+		glog.Warningf("Shutting down for %s", s)
+		stop()
+	}()
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
@@ -38,6 +41,37 @@ type hubMessageMeta struct {
 type centrifugoBroadcastParams struct {
 	Channels []string        `json:"channels"`
 	Data     json.RawMessage `json:"data"`
+
+	// Users and ChannelTemplate allow a producer to fan out to many personal
+	// channels without listing each one. They are expanded into Channels before
+	// the command is sent to centrifugo so are never included in the output.
+	Users           []string `json:"users,omitempty"`
+	ChannelTemplate string   `json:"channel_template,omitempty"`
+
+	// KeyID and Signature authenticate the producer. They are checked and cleared
+	// before the command is sent to centrifugo.
+	KeyID     string `json:"kid,omitempty"`
+	Signature string `json:"sig,omitempty"`
+}
+
+// userPlaceholder is replaced by each user name in a ChannelTemplate.
+const userPlaceholder = "{user}"
+
+// expandUserChannels appends a channel for each of Users to Channels using
+// ChannelTemplate, so `notifications#{user}` with users `["bob"]` becomes
+// `notifications#bob`. Users and ChannelTemplate are cleared afterwards.
+func (p *centrifugoBroadcastParams) expandUserChannels() {
+	if len(p.Users) < 1 {
+		return
+	}
+	channels := make([]string, 0, len(p.Channels)+len(p.Users))
+	channels = append(channels, p.Channels...)
+	for _, user := range p.Users {
+		channels = append(channels, strings.Replace(p.ChannelTemplate, userPlaceholder, user, -1))
+	}
+	p.Channels = channels
+	p.Users = nil
+	p.ChannelTemplate = ""
 }
 
 type centrifugoApiCommand struct {
@@ -49,6 +83,45 @@ type centrifugoRedisRequest struct {
 	Data []centrifugoApiCommand `json:"data"`
 }
 
+// splitEnvelopes splits a raw Scribe message into the envelopes it holds. A
+// message can be a single JSON envelope, a JSON array of envelopes, or several
+// envelopes separated by whitespace (usually newlines, i.e. NDJSON). If part of
+// the message isn't valid JSON, the whole raw message is returned after any
+// envelopes read before the error so that parseMessage reports it as invalid.
+func splitEnvelopes(raw []byte) [][]byte {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var list []json.RawMessage
+		if err := json.Unmarshal(trimmed, &list); err == nil {
+			out := make([][]byte, len(list))
+			for i, item := range list {
+				out[i] = item
+			}
+			return out
+		}
+		return [][]byte{raw}
+	}
+
+	var out [][]byte
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		var item json.RawMessage
+		err := dec.Decode(&item)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Hand it on to be counted as one invalid message
+			return append(out, raw)
+		}
+		out = append(out, item)
+	}
+	if len(out) < 1 {
+		return [][]byte{raw}
+	}
+	return out
+}
+
 // parseMessage attempts to parse an incoming raw JSON payload.
 // On success it return a centrifugoPublishParams struct ready to be
 // Marshalled to JSON. If there is an error parsing, or if the message TTL indicates
@@ -62,8 +135,8 @@ func parseMessage(bytes []byte) (*centrifugoBroadcastParams, error) {
 	}
 
 	// Sanity check it since Unmarshal doesn't require all struct fields to be set
-	if len(msg.Channels) < 1 || len(msg.Data) < 1 {
-		return nil, errors.New("No channel or data payload in message JSON")
+	if err := msg.validate(); err != nil {
+		return nil, err
 	}
 
 	// See if the Data payload is hub format with ts + ttl
@@ -86,10 +159,34 @@ func parseMessage(bytes []byte) (*centrifugoBroadcastParams, error) {
 	}
 
 	// We have ts + ttl, check if message has expired
-	now := time.Now()
-	if int64(meta.Ts+meta.TTL) < now.Unix() {
-		return nil, NewMessageStaleErr(meta.Ts, meta.TTL, now)
+	if err := checkStale(meta.Ts, meta.TTL); err != nil {
+		return nil, err
 	}
 
 	return &msg, nil
 }
+
+// validate checks a decoded envelope has everything needed to publish it
+func (p *centrifugoBroadcastParams) validate() error {
+	if (len(p.Channels) < 1 && len(p.Users) < 1) || len(p.Data) < 1 {
+		return errors.New("No channel or data payload in message JSON")
+	}
+	if len(p.Users) > 0 && !strings.Contains(p.ChannelTemplate, userPlaceholder) {
+		return fmt.Errorf("Message has users but channel_template %q does not contain %s",
+			p.ChannelTemplate, userPlaceholder)
+	}
+	return nil
+}
+
+// checkStale returns a MessageStaleErr if a message sent at ts with ttl has
+// expired. A zero ts or ttl never expires.
+func checkStale(ts, ttl uint32) error {
+	if ts == 0 || ttl == 0 {
+		return nil
+	}
+	now := time.Now()
+	if int64(ts+ttl) < now.Unix() {
+		return NewMessageStaleErr(ts, ttl, now)
+	}
+	return nil
+}
@@ -47,6 +47,31 @@ func TestParsingJSONMessage(t *testing.T) {
 			expectErr:     true,
 			expectErrType: nil,
 		},
+		{
+			name:          "Users without channel template",
+			input:         "{\"users\":[\"bob\"], \"data\":{\"foo\":\"bar\"}}",
+			expectOut:     nil,
+			expectErr:     true,
+			expectErrType: nil,
+		},
+		{
+			name:          "Users with template missing placeholder",
+			input:         "{\"users\":[\"bob\"], \"channel_template\":\"notifications\", \"data\":{\"foo\":\"bar\"}}",
+			expectOut:     nil,
+			expectErr:     true,
+			expectErrType: nil,
+		},
+		{
+			name:  "Correct format JSON - users and channel template",
+			input: "{\"users\":[\"bob\", \"alice\"], \"channel_template\":\"notifications#{user}\", \"data\":{\"foo\":\"bar\"}}",
+			expectOut: &centrifugoBroadcastParams{
+				Data:            json.RawMessage("{\"foo\":\"bar\"}"),
+				Users:           []string{"bob", "alice"},
+				ChannelTemplate: "notifications#{user}",
+			},
+			expectErr:     false,
+			expectErrType: nil,
+		},
 		{
 			name:  "Correct format JSON - no TTL",
 			input: "{\"channels\":[\"test\"], \"data\":{\"foo\":\"bar\"}}",
@@ -99,7 +124,7 @@ func TestParsingJSONMessage(t *testing.T) {
 				continue
 			}
 			if test.expectErrType != nil && reflect.TypeOf(err) != test.expectErrType {
-				t.Errorf("Failed case %s: expected error of type %v, got %v", test.expectErrType, err)
+				t.Errorf("Failed case %s: expected error of type %v, got %v", test.name, test.expectErrType, err)
 				continue
 			}
 		}
@@ -114,3 +139,55 @@ func TestParsingJSONMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitEnvelopes(t *testing.T) {
+	type testCase struct {
+		name      string
+		input     string
+		expectOut []string
+	}
+
+	tests := []testCase{
+		{
+			name:      "Single envelope",
+			input:     "{\"channels\":[\"a\"], \"data\":{}}",
+			expectOut: []string{"{\"channels\":[\"a\"], \"data\":{}}"},
+		},
+		{
+			name:      "Pretty printed envelope",
+			input:     "{\n  \"channels\": [\"a\"],\n  \"data\": {}\n}\n",
+			expectOut: []string{"{\n  \"channels\": [\"a\"],\n  \"data\": {}\n}"},
+		},
+		{
+			name:      "Array of envelopes",
+			input:     " [{\"channels\":[\"a\"]}, {\"channels\":[\"b\"]}]",
+			expectOut: []string{"{\"channels\":[\"a\"]}", "{\"channels\":[\"b\"]}"},
+		},
+		{
+			name:      "NDJSON",
+			input:     "{\"channels\":[\"a\"]}\n\n{\"channels\":[\"b\"]}\n",
+			expectOut: []string{"{\"channels\":[\"a\"]}", "{\"channels\":[\"b\"]}"},
+		},
+		{
+			name:      "NDJSON with invalid line",
+			input:     "{\"channels\":[\"a\"]}\n{sadsada",
+			expectOut: []string{"{\"channels\":[\"a\"]}", "{\"channels\":[\"a\"]}\n{sadsada"},
+		},
+		{
+			name:      "Empty",
+			input:     "",
+			expectOut: []string{""},
+		},
+	}
+
+	for _, test := range tests {
+		out := splitEnvelopes([]byte(test.input))
+		got := make([]string, len(out))
+		for i, b := range out {
+			got[i] = string(b)
+		}
+		if !reflect.DeepEqual(test.expectOut, got) {
+			t.Errorf("Failed case %s: expected %q got %q", test.name, test.expectOut, got)
+		}
+	}
+}
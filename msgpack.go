@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MessagePack envelopes are a map with the same keys as the JSON envelope:
+// channels, data, and optionally users and channel_template. Data can either be
+// a string/binary holding JSON text, which is passed through without being
+// decoded, or any other value, which is converted to JSON. ts and ttl can be given in the
+// envelope itself, or in data as in the hub format. A signature can be
+// given as kid and sig, with sig either hex text or the raw HMAC as binary.
+//
+// A message can also be an array of envelopes.
+
+// Limit on nesting to stop malicious input exhausting the stack
+const msgpackMaxDepth = 64
+
+var errMsgpackShort = errors.New("msgpack value truncated")
+
+func decodeMsgpackEnvelopes(raw []byte) []decodedMessage {
+	n, items, isArray, err := msgpackArrayHeader(raw)
+	if err != nil {
+		return []decodedMessage{{raw: raw, err: err}}
+	}
+	if !isArray {
+		v, rest, err := decodeMsgpackValue(raw, 0)
+		if err == nil && len(rest) > 0 {
+			err = errors.New("trailing bytes after msgpack value")
+		}
+		if err != nil {
+			return []decodedMessage{{raw: raw, err: err}}
+		}
+		msg, err := msgpackEnvelope(v)
+		return []decodedMessage{{msg: msg, raw: raw, err: err}}
+	}
+
+	// Decode each envelope separately so raw is only its own bytes, for size limits
+	out := make([]decodedMessage, 0, n)
+	for i := 0; i < n; i++ {
+		v, rest, err := decodeMsgpackValue(items, 1)
+		if err != nil {
+			return []decodedMessage{{raw: raw, err: err}}
+		}
+		msg, err := msgpackEnvelope(v)
+		out = append(out, decodedMessage{msg: msg, raw: items[:len(items)-len(rest)], err: err})
+		items = rest
+	}
+	if len(items) > 0 {
+		return []decodedMessage{{raw: raw, err: errors.New("trailing bytes after msgpack value")}}
+	}
+	return out
+}
+
+// msgpackArrayHeader returns the length and elements of b if it is an array
+func msgpackArrayHeader(b []byte) (int, []byte, bool, error) {
+	if len(b) < 1 {
+		return 0, nil, false, errMsgpackShort
+	}
+	c := b[0]
+	switch {
+	case c >= 0x90 && c <= 0x9f:
+		n := int(c & 0x0f)
+		if n > len(b)-1 {
+			return 0, nil, false, errMsgpackShort
+		}
+		return n, b[1:], true, nil
+	case c == 0xdc, c == 0xdd:
+		n, rest, err := msgpackLength(b[1:], c-0xdc+1)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		// Every element takes at least one byte so a larger count must be invalid
+		if n > len(rest) {
+			return 0, nil, false, errMsgpackShort
+		}
+		return n, rest, true, nil
+	}
+	return 0, nil, false, nil
+}
+
+// msgpackEnvelope converts a decoded envelope map into broadcast params
+func msgpackEnvelope(v interface{}) (*centrifugoBroadcastParams, error) {
+	env, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("msgpack envelope is not a map")
+	}
+
+	var msg centrifugoBroadcastParams
+	var err error
+	if msg.Channels, err = msgpackStrings(env["channels"]); err != nil {
+		return nil, err
+	}
+	if msg.Users, err = msgpackStrings(env["users"]); err != nil {
+		return nil, err
+	}
+	if tmpl, ok := env["channel_template"]; ok {
+		if msg.ChannelTemplate, ok = tmpl.(string); !ok {
+			return nil, errors.New("msgpack channel_template is not a string")
+		}
+	}
+
+	if kid, ok := env["kid"]; ok {
+		if msg.KeyID, ok = kid.(string); !ok {
+			return nil, errors.New("msgpack kid is not a string")
+		}
+	}
+	switch sig := env["sig"].(type) {
+	case string:
+		msg.Signature = sig
+	case []byte:
+		msg.Signature = hex.EncodeToString(sig)
+	case nil:
+	default:
+		return nil, errors.New("msgpack sig is not a string or binary")
+	}
+
+	// Data given as JSON text is checked, while anything else was decoded from
+	// msgpack so is always valid once converted to JSON
+	var dataTs, dataTTL uint32
+	isText := false
+	switch data := env["data"].(type) {
+	case string:
+		msg.Data, isText = json.RawMessage(data), true
+	case []byte:
+		msg.Data, isText = json.RawMessage(data), true
+	case nil:
+	default:
+		if msg.Data, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+		if m, ok := data.(map[string]interface{}); ok {
+			dataTs, dataTTL = msgpackUint32(m["ts"]), msgpackUint32(m["ttl"])
+		}
+	}
+	if isText && len(msg.Data) > 0 {
+		if err := validJSONData(msg.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+	ts, ttl := msgpackUint32(env["ts"]), msgpackUint32(env["ttl"])
+	if ts == 0 && ttl == 0 {
+		if isText {
+			ts, ttl = dataExpiry(msg.Data)
+		} else {
+			ts, ttl = dataTs, dataTTL
+		}
+	}
+	if err := checkStale(ts, ttl); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func msgpackStrings(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("msgpack expected an array of strings")
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		if out[i], ok = item.(string); !ok {
+			return nil, errors.New("msgpack expected an array of strings")
+		}
+	}
+	return out, nil
+}
+
+func msgpackUint32(v interface{}) uint32 {
+	switch n := v.(type) {
+	case int64:
+		if n > 0 && n <= math.MaxUint32 {
+			return uint32(n)
+		}
+	case uint64:
+		if n <= math.MaxUint32 {
+			return uint32(n)
+		}
+	case float64:
+		if n > 0 && n <= math.MaxUint32 {
+			return uint32(n)
+		}
+	}
+	return 0
+}
+
+// decodeMsgpackValue decodes one MessagePack value from b returning it and the
+// remaining bytes. Maps must have string keys. Integers decode as int64, or
+// uint64 if too large, floats as float64, strings as string and binary as []byte.
+// Extension types are not supported.
+func decodeMsgpackValue(b []byte, depth int) (interface{}, []byte, error) {
+	if depth > msgpackMaxDepth {
+		return nil, nil, errors.New("msgpack value nested too deeply")
+	}
+	if len(b) < 1 {
+		return nil, nil, errMsgpackShort
+	}
+	c, b := b[0], b[1:]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c >= 0xa0 && c <= 0xbf:
+		return msgpackStr(b, int(c&0x1f))
+	case c >= 0x90 && c <= 0x9f:
+		return msgpackArray(b, int(c&0x0f), depth)
+	case c >= 0x80 && c <= 0x8f:
+		return msgpackMap(b, int(c&0x0f), depth)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, b, err := msgpackLength(b, c-0xc4)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(b) < n {
+			return nil, nil, errMsgpackShort
+		}
+		return append([]byte(nil), b[:n]...), b[n:], nil
+	case 0xca:
+		if len(b) < 4 {
+			return nil, nil, errMsgpackShort
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xcb:
+		if len(b) < 8 {
+			return nil, nil, errMsgpackShort
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (c - 0xcc)
+		if len(b) < size {
+			return nil, nil, errMsgpackShort
+		}
+		n := msgpackUint(b[:size])
+		if n > math.MaxInt64 {
+			return n, b[size:], nil
+		}
+		return int64(n), b[size:], nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		if len(b) < size {
+			return nil, nil, errMsgpackShort
+		}
+		// Sign extend from the top bit of the value
+		shift := uint(64 - 8*size)
+		return int64(msgpackUint(b[:size])<<shift) >> shift, b[size:], nil
+	case 0xd9, 0xda, 0xdb:
+		n, b, err := msgpackLength(b, c-0xd9)
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackStr(b, n)
+	case 0xdc, 0xdd:
+		n, b, err := msgpackLength(b, c-0xdc+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackArray(b, n, depth)
+	case 0xde, 0xdf:
+		n, b, err := msgpackLength(b, c-0xde+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackMap(b, n, depth)
+	}
+	return nil, nil, fmt.Errorf("unsupported msgpack type 0x%x", c)
+}
+
+func msgpackUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// msgpackLength reads a 1, 2 or 4 byte length for sizeClass 0, 1 or 2
+func msgpackLength(b []byte, sizeClass byte) (int, []byte, error) {
+	size := 1 << sizeClass
+	if len(b) < size {
+		return 0, nil, errMsgpackShort
+	}
+	n := msgpackUint(b[:size])
+	if n > uint64(math.MaxInt32) {
+		return 0, nil, errors.New("msgpack length too large")
+	}
+	return int(n), b[size:], nil
+}
+
+func msgpackStr(b []byte, n int) (interface{}, []byte, error) {
+	if len(b) < n {
+		return nil, nil, errMsgpackShort
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func msgpackArray(b []byte, n int, depth int) (interface{}, []byte, error) {
+	// Every element takes at least one byte so a larger count must be invalid
+	if n > len(b) {
+		return nil, nil, errMsgpackShort
+	}
+	out := make([]interface{}, n)
+	for i := range out {
+		var err error
+		if out[i], b, err = decodeMsgpackValue(b, depth+1); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, b, nil
+}
+
+func msgpackMap(b []byte, n int, depth int) (interface{}, []byte, error) {
+	if 2*n > len(b) {
+		return nil, nil, errMsgpackShort
+	}
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, rest, err := decodeMsgpackValue(b, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, errors.New("msgpack map key is not a string")
+		}
+		if out[key], b, err = decodeMsgpackValue(rest, depth+1); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, b, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Field numbers of the Envelope message in envelope.proto
+const (
+	protoFieldChannels        = 1
+	protoFieldData            = 2
+	protoFieldTs              = 3
+	protoFieldTTL             = 4
+	protoFieldUsers           = 5
+	protoFieldChannelTemplate = 6
+	protoFieldKeyID           = 7
+	protoFieldSignature       = 8
+)
+
+// Protobuf wire types
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+var errProtoShort = errors.New("protobuf message truncated")
+
+func decodeProtobufEnvelopes(raw []byte) []decodedMessage {
+	msg, err := decodeProtobufEnvelope(raw)
+	return []decodedMessage{{msg: msg, raw: raw, err: err}}
+}
+
+// decodeProtobufEnvelope decodes an Envelope as defined in envelope.proto. The
+// schema is small and stable enough that decoding the wire format directly is
+// simpler than depending on a protobuf runtime.
+func decodeProtobufEnvelope(b []byte) (*centrifugoBroadcastParams, error) {
+	var msg centrifugoBroadcastParams
+	var ts, ttl uint32
+
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errProtoShort
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&0x7
+
+		var value []byte
+		var varint uint64
+		switch wireType {
+		case protoWireVarint:
+			if varint, n = binary.Uvarint(b); n <= 0 {
+				return nil, errProtoShort
+			}
+			b = b[n:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return nil, errProtoShort
+			}
+			value = b[n : n+int(length)]
+			b = b[n+int(length):]
+		case protoWireFixed64, protoWireFixed32:
+			size := 8
+			if wireType == protoWireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return nil, errProtoShort
+			}
+			b = b[size:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+
+		expectBytes := field == protoFieldChannels || field == protoFieldData ||
+			field == protoFieldUsers || field == protoFieldChannelTemplate ||
+			field == protoFieldKeyID || field == protoFieldSignature
+		expectVarint := field == protoFieldTs || field == protoFieldTTL
+		if (expectBytes && wireType != protoWireBytes) || (expectVarint && wireType != protoWireVarint) {
+			return nil, fmt.Errorf("protobuf field %d has wrong wire type %d", field, wireType)
+		}
+
+		switch field {
+		case protoFieldChannels:
+			msg.Channels = append(msg.Channels, string(value))
+		case protoFieldData:
+			msg.Data = append([]byte(nil), value...)
+		case protoFieldTs:
+			ts = uint32(varint)
+		case protoFieldTTL:
+			ttl = uint32(varint)
+		case protoFieldUsers:
+			msg.Users = append(msg.Users, string(value))
+		case protoFieldChannelTemplate:
+			msg.ChannelTemplate = string(value)
+		case protoFieldKeyID:
+			msg.KeyID = string(value)
+		case protoFieldSignature:
+			msg.Signature = hex.EncodeToString(value)
+		}
+		// Unknown fields are skipped so the schema can grow
+	}
+
+	if len(msg.Data) > 0 {
+		if err := validJSONData(msg.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+	if ts == 0 && ttl == 0 {
+		ts, ttl = dataExpiry(msg.Data)
+	}
+	if err := checkStale(ts, ttl); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// Category HTTP published messages are routed as if none is given
+const defaultPublishCategory = "http"
+
+// batchLogger publishes a batch of Scribe entries. It is implemented by Handler.
+type batchLogger interface {
+	logBatch(messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext)
+}
+
+// publishResponse is the body of a successful publish response
+type publishResponse struct {
+	Accepted int `json:"accepted"`
+	Dropped  int `json:"dropped"`
+}
+
+// publishHTTPHandler publishes JSON envelopes POSTed to it without needing a
+// Scribe client. The body is handled exactly like one Scribe message, so it can
+// be a single envelope, an array of them or NDJSON. The category to route it as
+// is given by the `category` query parameter.
+type publishHTTPHandler struct {
+	handler batchLogger
+	tokens  bearerTokens
+	sd      statsd.Statsd
+}
+
+func (h *publishHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Messages must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.tokens.authorized(r) {
+		h.sd.Incr("http_publish.unauthorized", 1)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	if err != nil {
+		glog.Warningf("Failed to read publish request from %s. err: %s", r.RemoteAddr, err)
+		h.sd.Incr("error.http_publish_fail", 1)
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+	h.sd.Incr("http_publish.requests", 1)
+
+	category := r.URL.Query().Get("category")
+	if category == "" {
+		category = defaultPublishCategory
+	}
+	result, batch := h.handler.logBatch([]*scribe.LogEntry{{Category: category, Message: string(body)}})
+	if result == scribe.ResultCode_TRY_LATER {
+		// Nothing was published so the whole request can be retried
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Failed to publish, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&publishResponse{Accepted: batch.accepted, Dropped: batch.dropped})
+}
@@ -0,0 +1,110 @@
+#!/usr/local/bin/thrift --gen go
+
+#
+# Typed alternative to sending JSON envelopes in Scribe LogEntry messages.
+# Served alongside scribe.thrift on the same listeners, so producers that
+# control their client can publish without encoding and us decoding JSON.
+# Requests go through the same routing, limits and signature checks.
+#
+
+namespace java com.deviantart.centrifugo_scriber
+namespace php CentrifugoScriber
+
+/**
+ * Optional extras for a PublishRequest, as in JSON envelopes
+ */
+struct PublishOptions
+{
+  /**
+   * Users to fan out to using channel_template, which must contain {user}
+   */
+  1:  list<string> users,
+  2:  string channel_template,
+  /**
+   * Id of the keyring key the request is signed with, and the raw HMAC-SHA256
+   * over its channels and data
+   */
+  3:  string key_id,
+  4:  binary signature
+}
+
+struct PublishRequest
+{
+  /**
+   * Channels to broadcast to
+   */
+  1:  list<string> channels,
+  /**
+   * JSON encoded payload delivered to clients, usually an object. It is passed
+   * through to centrifugo as is.
+   */
+  2:  binary data,
+  /**
+   * UNIX timestamp (seconds) the event occurred and time to live in seconds.
+   * If both are set and the request has expired it is dropped. If neither is
+   * set, ts and ttl inside data are checked as in JSON envelopes.
+   */
+  3:  i64 ts,
+  4:  i32 ttl,
+  /**
+   * Centrifugo API method, only broadcast (the default if empty) is supported
+   */
+  5:  string method,
+  6:  optional PublishOptions options,
+  /**
+   * Category to route the request as, for rules in the config. Defaults to
+   * "thrift".
+   */
+  7:  string category
+}
+
+/**
+ * What happened to one PublishRequest
+ */
+enum PublishStatus
+{
+  PUBLISHED,
+  DEBOUNCED,
+  INVALID,
+  STALE,
+  UNAUTHORIZED,
+  DUPLICATE,
+  FILTERED,
+  TOO_LARGE,
+  RATE_LIMITED
+}
+
+/**
+ * Result of one PublishRequest, at its position in Publish's results
+ */
+struct RequestResult
+{
+  1:  PublishStatus status,
+  /**
+   * Why the request was dropped, as in the dropped.* metrics
+   */
+  2:  string reason
+}
+
+enum ErrorCode
+{
+  /**
+   * Nothing was published and the whole call should be retried
+   */
+  TRY_LATER
+}
+
+exception PublishError
+{
+  1:  ErrorCode code,
+  2:  string message
+}
+
+service Publisher
+{
+  /**
+   * Publishes requests, returning a result for each in the same order.
+   * Throws PublishError if none could be published.
+   */
+  list<RequestResult> Publish(1: list<PublishRequest> requests) throws (1: PublishError error);
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// fakeLogger parses batches like Handler but returns a fixed result instead of
+// pushing to redis
+type fakeLogger struct {
+	result   scribe.ResultCode
+	category string
+}
+
+func (l *fakeLogger) logBatch(messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext) {
+	l.category = messages[0].Category
+	batch := &batchContext{}
+	scribeEntriesToBroadcastCommand(messages, batch, &statsd.NoopClient{})
+	return l.result, batch
+}
+
+func TestPublishHTTP(t *testing.T) {
+	type testCase struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		token          string
+		result         scribe.ResultCode
+		expectStatus   int
+		expectResponse *publishResponse
+		expectCategory string
+	}
+
+	valid := "{\"channels\":[\"foo\"], \"data\":{\"foo\": 1}}"
+	tests := []testCase{
+		{
+			name:           "Single envelope",
+			body:           valid,
+			expectStatus:   http.StatusAccepted,
+			expectResponse: &publishResponse{Accepted: 1},
+			expectCategory: defaultPublishCategory,
+		},
+		{
+			name:           "Array with an invalid envelope",
+			url:            "/publish?category=events",
+			body:           "[" + valid + ", {\"channels\":[]}, " + valid + "]",
+			expectStatus:   http.StatusAccepted,
+			expectResponse: &publishResponse{Accepted: 2, Dropped: 1},
+			expectCategory: "events",
+		},
+		{
+			name:           "NDJSON",
+			body:           valid + "\n" + valid + "\n",
+			expectStatus:   http.StatusAccepted,
+			expectResponse: &publishResponse{Accepted: 2},
+			expectCategory: defaultPublishCategory,
+		},
+		{
+			name:         "Redis unavailable",
+			body:         valid,
+			result:       scribe.ResultCode_TRY_LATER,
+			expectStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "Bad token",
+			body:         valid,
+			token:        "bad",
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "GET",
+			method:       "GET",
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		logger := &fakeLogger{result: test.result}
+		h := &publishHTTPHandler{handler: logger, tokens: parseTokens("secret"), sd: &statsd.NoopClient{}}
+
+		method, url, token := test.method, test.url, test.token
+		if method == "" {
+			method = "POST"
+		}
+		if url == "" {
+			url = "/publish"
+		}
+		if token == "" {
+			token = "secret"
+		}
+		req := httptest.NewRequest(method, url, strings.NewReader(test.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != test.expectStatus {
+			t.Errorf("Failed case %s: expected status %d got %d", test.name, test.expectStatus, rec.Code)
+			continue
+		}
+		if test.expectResponse == nil {
+			continue
+		}
+		var resp publishResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Errorf("Failed case %s: invalid response %q", test.name, rec.Body.String())
+			continue
+		}
+		if resp != *test.expectResponse {
+			t.Errorf("Failed case %s: expected response %+v got %+v", test.name, *test.expectResponse, resp)
+		}
+		if logger.category != test.expectCategory {
+			t.Errorf("Failed case %s: expected category %s got %s", test.name, test.expectCategory, logger.category)
+		}
+	}
+}
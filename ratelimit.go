@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/gopkg.in/bsm/ratelimit.v1"
+)
+
+// Overflow actions for rate limited messages
+const (
+	overflowDrop  = "drop"
+	overflowDelay = "delay"
+)
+
+const (
+	// How long a bucket can go unused before it is forgotten
+	rateLimitIdleTimeout = 10 * time.Minute
+	// How often to look for idle buckets
+	rateLimitSweepInterval = 1 * time.Minute
+)
+
+// rateLimitConfig limits how fast messages are published to each channel in a
+// namespace, and to the namespace as a whole. Every channel a message is broadcast
+// to takes one token from its channel bucket and one from the namespace bucket.
+type rateLimitConfig struct {
+	Channel   *bucketConfig `json:"channel"`
+	Namespace *bucketConfig `json:"namespace"`
+	// Overflow is what to do with a message over the limit: "drop" (the default)
+	// or "delay" which waits up to MaxDelay milliseconds for a token before dropping.
+	// Delaying holds up the whole batch so Scribe backs off while we catch up.
+	Overflow string `json:"overflow"`
+	MaxDelay int    `json:"max_delay_ms"`
+}
+
+// bucketConfig is a token bucket refilled at Rate tokens per second which can
+// hold up to Burst tokens.
+type bucketConfig struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func (c *rateLimitConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, b := range []*bucketConfig{c.Channel, c.Namespace} {
+		if b != nil && (b.Rate <= 0 || b.Burst < 1) {
+			return errors.New("rate limit rate and burst must be positive")
+		}
+	}
+	switch c.Overflow {
+	case "", overflowDrop:
+	case overflowDelay:
+		if c.MaxDelay < 1 {
+			return errors.New("delay overflow requires max_delay_ms")
+		}
+	default:
+		return errors.New("rate limit overflow must be drop or delay")
+	}
+	return nil
+}
+
+// rateLimiters holds token buckets for every recently used channel and namespace.
+// They are kept separately from config so reloading doesn't reset them.
+type rateLimiters struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *ratelimit.RateLimiter
+	config   bucketConfig
+	lastUsed time.Time
+}
+
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// get returns the limiter for key, creating it if it doesn't exist or if its
+// config changed
+func (r *rateLimiters) get(key string, cfg bucketConfig) *ratelimit.RateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastSweep) > rateLimitSweepInterval {
+		for k, b := range r.buckets {
+			if now.Sub(b.lastUsed) > rateLimitIdleTimeout {
+				delete(r.buckets, k)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	b, ok := r.buckets[key]
+	if !ok || b.config != cfg {
+		// ratelimit allows rate calls per duration with an equal burst, so express
+		// our rate as burst tokens per the time it takes to refill them
+		per := time.Duration(float64(cfg.Burst) / cfg.Rate * float64(time.Second))
+		b = &bucket{
+			limiter: ratelimit.New(cfg.Burst, per),
+			config:  cfg,
+		}
+		r.buckets[key] = b
+	}
+	b.lastUsed = now
+	return b.limiter
+}
+
+// take takes a token from limiter, waiting for one until deadline if the
+// overflow action is delay. It returns false if the message should be dropped.
+func take(limiter *ratelimit.RateLimiter, bc *bucketConfig, cfg *rateLimitConfig, deadline time.Time) bool {
+	if !limiter.Limit() {
+		return true
+	}
+	if cfg.Overflow != overflowDelay {
+		return false
+	}
+
+	step := time.Duration(float64(time.Second) / bc.Rate)
+	for time.Now().Before(deadline) {
+		if wait := deadline.Sub(time.Now()); wait < step {
+			step = wait
+		}
+		time.Sleep(step)
+		if !limiter.Limit() {
+			return true
+		}
+	}
+	return false
+}
+
+// limit returns the channels in namespace that are within the configured limits.
+// With the delay overflow action the whole message waits at most MaxDelay, however
+// many channels it has. It is safe to call on nil rateLimiters or with a nil config
+// which allow everything.
+func (r *rateLimiters) limit(namespace string, channels []string, cfg *rateLimitConfig) []string {
+	if r == nil || cfg == nil {
+		return channels
+	}
+
+	deadline := time.Now().Add(time.Duration(cfg.MaxDelay) * time.Millisecond)
+	allowed := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		var chLimiter *ratelimit.RateLimiter
+		if cfg.Channel != nil {
+			chLimiter = r.get("channel:"+ch, *cfg.Channel)
+			if !take(chLimiter, cfg.Channel, cfg, deadline) {
+				continue
+			}
+		}
+		if cfg.Namespace != nil && !take(r.get("namespace:"+namespace, *cfg.Namespace), cfg.Namespace, cfg, deadline) {
+			// Give back the channel token since nothing was published
+			if chLimiter != nil {
+				chLimiter.Undo()
+			}
+			continue
+		}
+		allowed = append(allowed, ch)
+	}
+	return allowed
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestRateLimitDropsOverBurst(t *testing.T) {
+	r := newRateLimiters()
+	cfg := &rateLimitConfig{
+		Channel: &bucketConfig{Rate: 1, Burst: 2},
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := r.limit("", []string{"foo", "bar"}, cfg); !reflect.DeepEqual(got, []string{"foo", "bar"}) {
+			t.Fatalf("Call %d: expected both channels within burst, got %v", i, got)
+		}
+	}
+	if got := r.limit("", []string{"foo", "baz"}, cfg); !reflect.DeepEqual(got, []string{"baz"}) {
+		t.Errorf("Expected only new channel to be allowed, got %v", got)
+	}
+}
+
+func TestRateLimitNamespace(t *testing.T) {
+	r := newRateLimiters()
+	cfg := &rateLimitConfig{
+		Channel:   &bucketConfig{Rate: 1, Burst: 5},
+		Namespace: &bucketConfig{Rate: 1, Burst: 3},
+	}
+
+	got := r.limit("public", []string{"public:a", "public:b", "public:c", "public:d"}, cfg)
+	if !reflect.DeepEqual(got, []string{"public:a", "public:b", "public:c"}) {
+		t.Errorf("Expected namespace burst to limit to 3 channels, got %v", got)
+	}
+	// Another namespace has its own bucket
+	got = r.limit("private", []string{"private:a"}, cfg)
+	if !reflect.DeepEqual(got, []string{"private:a"}) {
+		t.Errorf("Expected other namespace to be allowed, got %v", got)
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	r := newRateLimiters()
+	cfg := &rateLimitConfig{
+		Channel:  &bucketConfig{Rate: 50, Burst: 1},
+		Overflow: overflowDelay,
+		MaxDelay: 200,
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if got := r.limit("", []string{"foo"}, cfg); len(got) != 1 {
+			t.Fatalf("Call %d: expected message to be delayed not dropped", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected calls over the limit to be delayed, took %v", elapsed)
+	}
+}
+
+func TestRateLimitDelayPerMessage(t *testing.T) {
+	r := newRateLimiters()
+	cfg := &rateLimitConfig{
+		Channel:   &bucketConfig{Rate: 1, Burst: 1},
+		Namespace: &bucketConfig{Rate: 1, Burst: 1},
+		Overflow:  overflowDelay,
+		MaxDelay:  50,
+	}
+	channels := []string{"a", "b", "c", "d", "e"}
+	r.limit("", channels, cfg)
+
+	// Every channel and the namespace are out of tokens, but the message only
+	// waits max_delay_ms in total rather than for each channel and bucket
+	start := time.Now()
+	if got := r.limit("", channels, cfg); len(got) != 0 {
+		t.Errorf("Expected all channels to be limited got %v", got)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected message to wait at most about 50ms, took %v", elapsed)
+	}
+}
+
+func TestRateLimitConfigValidation(t *testing.T) {
+	invalid := []*rateLimitConfig{
+		{Channel: &bucketConfig{Rate: 0, Burst: 1}},
+		{Namespace: &bucketConfig{Rate: 1, Burst: 0}},
+		{Overflow: "explode"},
+		{Overflow: overflowDelay},
+	}
+	for i, c := range invalid {
+		if err := c.validate(); err == nil {
+			t.Errorf("Failed case %d: expected %+v to be invalid", i, c)
+		}
+	}
+}
+
+func TestRateLimitCountedPerMessage(t *testing.T) {
+	cfg := &Config{Namespaces: map[string]*routeConfig{
+		"ns": {RateLimit: &rateLimitConfig{Channel: &bucketConfig{Rate: 1, Burst: 1}}},
+	}}
+	batch := &batchContext{config: cfg, limiters: newRateLimiters()}
+	sd := newStatsCounters(&statsd.NoopClient{})
+	entries := []*scribe.LogEntry{
+		{Category: "HUBD", Message: "{\"channels\":[\"ns:a\"], \"data\":{}}"},
+		// Loses one channel but is still published
+		{Category: "HUBD", Message: "{\"channels\":[\"ns:a\",\"ns:b\"], \"data\":{}}"},
+		// Loses every channel so is dropped
+		{Category: "HUBD", Message: "{\"channels\":[\"ns:a\",\"ns:b\"], \"data\":{}}"},
+	}
+	out, _, err := scribeEntriesToBroadcastCommand(entries, batch, sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 2 {
+		t.Errorf("Expected 2 messages published got %d", len(out.Data))
+	}
+	if got := sd.get("dropped.rate_limited"); got != 1 {
+		t.Errorf("Expected 1 message dropped got %d", got)
+	}
+	if got := sd.get("rate_limited.channels"); got != 3 {
+		t.Errorf("Expected 3 channels limited got %d", got)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// entryResult counts what happened to the envelopes in one Scribe entry
+type entryResult struct {
+	published, debounced, dropped int
+	// reason is why the last dropped envelope was dropped
+	reason string
+}
+
+func (e *entryResult) drop(reason string) {
+	e.dropped++
+	e.reason = reason
+}
+
+// malformed reports whether the entry was dropped because it couldn't be
+// decoded
+func (e *entryResult) malformed() bool {
+	return e.published+e.debounced == 0 && (e.reason == "invalid_format" || e.reason == "decompress_fail")
+}
+
+// status summarises the entry for LogWithResults, given the result of
+// publishing its batch
+func (e *entryResult) status(result scribe.ResultCode) scribe.EntryStatus {
+	switch {
+	case e.published > 0 && result == scribe.ResultCode_TRY_LATER:
+		return scribe.EntryStatus_TRY_LATER
+	case e.published+e.debounced > 0 && e.dropped > 0:
+		return scribe.EntryStatus_PARTIAL
+	case e.published > 0:
+		return scribe.EntryStatus_PUBLISHED
+	case e.debounced > 0:
+		return scribe.EntryStatus_DEBOUNCED
+	}
+	return dropStatus(e.reason)
+}
+
+// dropStatus maps the reason a message was dropped, as in its dropped.*
+// metric, to the status reported for it
+func dropStatus(reason string) scribe.EntryStatus {
+	switch {
+	case reason == "stale_ttl":
+		return scribe.EntryStatus_STALE
+	case reason == "unsigned", reason == "bad_signature", reason == "client_not_allowed":
+		return scribe.EntryStatus_UNAUTHORIZED
+	case reason == "duplicate":
+		return scribe.EntryStatus_DUPLICATE
+	case reason == "rate_limited":
+		return scribe.EntryStatus_RATE_LIMITED
+	case reason == "script", reason == "sampled", strings.HasPrefix(reason, "filter."):
+		return scribe.EntryStatus_FILTERED
+	case reason == "decompressed_too_large", strings.HasPrefix(reason, "too_large."), strings.HasPrefix(reason, "dead_lettered."):
+		return scribe.EntryStatus_TOO_LARGE
+	}
+	return scribe.EntryStatus_INVALID
+}
+
+// entryResults returns the result of each entry in a batch published with result
+func entryResults(result scribe.ResultCode, batch *batchContext) []*scribe.EntryResult {
+	results := make([]*scribe.EntryResult, len(batch.entries))
+	for i := range batch.entries {
+		e := &batch.entries[i]
+		results[i] = &scribe.EntryResult{
+			Status:    e.status(result),
+			Published: int32(e.published + e.debounced),
+			Dropped:   int32(e.dropped),
+			Reason:    e.reason,
+		}
+	}
+	return results
+}
+
+// LogWithResults is Log returning what happened to each entry, so clients can
+// retry only those with status TRY_LATER and report the rest
+func (h *Handler) LogWithResults(messages []*scribe.LogEntry) ([]*scribe.EntryResult, error) {
+	r, batch := h.logBatch(messages)
+	return entryResults(r, batch), nil
+}
+
+func (c *clientHandler) LogWithResults(messages []*scribe.LogEntry) ([]*scribe.EntryResult, error) {
+	cfg := c.currentConfig().forListener(c.listener)
+	allowed := allowedEntries(cfg, c.subjects, messages, c.sd)
+	r, batch := c.logBatchConfig(cfg, allowed)
+
+	// Entries the client may not log were never part of the batch
+	byEntry := make(map[*scribe.LogEntry]*scribe.EntryResult, len(allowed))
+	for i, result := range entryResults(r, batch) {
+		byEntry[allowed[i]] = result
+	}
+	results := make([]*scribe.EntryResult, len(messages))
+	for i, m := range messages {
+		if result, ok := byEntry[m]; ok {
+			results[i] = result
+			continue
+		}
+		results[i] = &scribe.EntryResult{
+			Status:  scribe.EntryStatus_UNAUTHORIZED,
+			Dropped: 1,
+			Reason:  "client_not_allowed",
+		}
+	}
+	return results, nil
+}
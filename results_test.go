@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestEntryStatus(t *testing.T) {
+	type testCase struct {
+		name   string
+		entry  entryResult
+		result scribe.ResultCode
+		expect scribe.EntryStatus
+	}
+
+	tests := []testCase{
+		{"published", entryResult{published: 1}, scribe.ResultCode_OK, scribe.EntryStatus_PUBLISHED},
+		{"retry", entryResult{published: 1}, scribe.ResultCode_TRY_LATER, scribe.EntryStatus_TRY_LATER},
+		{"retry partial", entryResult{published: 1, dropped: 1}, scribe.ResultCode_TRY_LATER, scribe.EntryStatus_TRY_LATER},
+		{"debounced", entryResult{debounced: 2}, scribe.ResultCode_OK, scribe.EntryStatus_DEBOUNCED},
+		{"debounced during retry", entryResult{debounced: 1}, scribe.ResultCode_TRY_LATER, scribe.EntryStatus_DEBOUNCED},
+		{"partial", entryResult{published: 1, dropped: 1, reason: "stale_ttl"}, scribe.ResultCode_OK, scribe.EntryStatus_PARTIAL},
+		{"empty", entryResult{}, scribe.ResultCode_OK, scribe.EntryStatus_INVALID},
+		{"invalid", entryResult{dropped: 1, reason: "invalid_format"}, scribe.ResultCode_OK, scribe.EntryStatus_INVALID},
+		{"stale", entryResult{dropped: 1, reason: "stale_ttl"}, scribe.ResultCode_OK, scribe.EntryStatus_STALE},
+		{"unsigned", entryResult{dropped: 1, reason: "unsigned"}, scribe.ResultCode_OK, scribe.EntryStatus_UNAUTHORIZED},
+		{"duplicate", entryResult{dropped: 1, reason: "duplicate"}, scribe.ResultCode_OK, scribe.EntryStatus_DUPLICATE},
+		{"rate limited", entryResult{dropped: 1, reason: "rate_limited"}, scribe.ResultCode_OK, scribe.EntryStatus_RATE_LIMITED},
+		{"filtered", entryResult{dropped: 1, reason: "filter.deny"}, scribe.ResultCode_OK, scribe.EntryStatus_FILTERED},
+		{"sampled", entryResult{dropped: 1, reason: "sampled"}, scribe.ResultCode_OK, scribe.EntryStatus_FILTERED},
+		{"too large", entryResult{dropped: 1, reason: "too_large.message"}, scribe.ResultCode_OK, scribe.EntryStatus_TOO_LARGE},
+		{"dead lettered", entryResult{dropped: 1, reason: "dead_lettered.batch"}, scribe.ResultCode_OK, scribe.EntryStatus_TOO_LARGE},
+	}
+
+	for _, test := range tests {
+		if status := test.entry.status(test.result); status != test.expect {
+			t.Errorf("%s: expected %s got %s", test.name, test.expect, status)
+		}
+	}
+}
+
+func TestEntryResults(t *testing.T) {
+	now := time.Now()
+	valid := "{\"channels\":[\"foo\"], \"data\":{\"mid\":\"%s\"}}"
+	stale := fmt.Sprintf("{\"channels\":[\"foo\"], \"data\":{\"ts\": %d, \"ttl\":10}}", now.Add(-time.Minute).Unix())
+	messages := []*scribe.LogEntry{
+		{Category: "HUBD", Message: fmt.Sprintf(valid, "a")},
+		{Category: "HUBD", Message: stale},
+		{Category: "HUBD", Message: "not json"},
+		{Category: "HUBD", Message: fmt.Sprintf(valid, "a")},
+		{Category: "HUBD", Message: fmt.Sprintf(valid, "b") + "\n" + stale},
+	}
+	expect := []scribe.EntryResult{
+		{Status: scribe.EntryStatus_PUBLISHED, Published: 1},
+		{Status: scribe.EntryStatus_STALE, Dropped: 1, Reason: "stale_ttl"},
+		{Status: scribe.EntryStatus_INVALID, Dropped: 1, Reason: "invalid_format"},
+		{Status: scribe.EntryStatus_DUPLICATE, Dropped: 1, Reason: "duplicate"},
+		{Status: scribe.EntryStatus_PARTIAL, Published: 1, Dropped: 1, Reason: "stale_ttl"},
+	}
+
+	batch := &batchContext{dedup: newLocalDedupStore(time.Minute, 100)}
+	if _, _, err := scribeEntriesToBroadcastCommand(messages, batch, &statsd.NoopClient{}); err != nil {
+		t.Fatal(err)
+	}
+	results := entryResults(scribe.ResultCode_OK, batch)
+	if len(results) != len(expect) {
+		t.Fatalf("Expected %d results got %d", len(expect), len(results))
+	}
+	for i, result := range results {
+		if *result != expect[i] {
+			t.Errorf("Entry %d: expected %+v got %+v", i, expect[i], *result)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// Sampling modes
+const (
+	sampleRandom = "random"
+	sampleByMid  = "mid"
+)
+
+// sampleConfig publishes only a fraction of a namespace's messages. Rate is the
+// fraction kept, from 0 (drop everything) to 1 (keep everything). In "random" mode
+// (the default) each message is kept at random. In "mid" mode the decision is a
+// hash of the hub format `mid` so every scriber makes the same choice for a given
+// message; messages without a mid are sampled at random.
+type sampleConfig struct {
+	Rate float64 `json:"rate"`
+	Mode string  `json:"mode"`
+}
+
+func (c *sampleConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Rate < 0 || c.Rate > 1 {
+		return errors.New("sample rate must be between 0 and 1")
+	}
+	switch c.Mode {
+	case "", sampleRandom, sampleByMid:
+	default:
+		return errors.New("sample mode must be random or mid")
+	}
+	return nil
+}
+
+// keep reports whether a message should be published. It is safe to call on a
+// nil config which keeps everything.
+func (c *sampleConfig) keep(msg *centrifugoBroadcastParams) bool {
+	if c == nil || c.Rate >= 1 {
+		return true
+	}
+	if c.Mode == sampleByMid {
+		var meta struct {
+			Mid string `json:"mid"`
+		}
+		if err := json.Unmarshal(msg.Data, &meta); err == nil && meta.Mid != "" {
+			h := fnv.New32a()
+			h.Write([]byte(meta.Mid))
+			return float64(h.Sum32()) < c.Rate*float64(math.MaxUint32)
+		}
+	}
+	return rand.Float64() < c.Rate
+}
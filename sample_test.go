@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestSampleRates(t *testing.T) {
+	msg := &centrifugoBroadcastParams{Channels: []string{"a"}, Data: json.RawMessage("{}")}
+
+	var none *sampleConfig
+	if !none.keep(msg) {
+		t.Errorf("Expected no sampling config to keep messages")
+	}
+	if !(&sampleConfig{Rate: 1}).keep(msg) {
+		t.Errorf("Expected rate 1 to keep messages")
+	}
+	if (&sampleConfig{Rate: 0}).keep(msg) {
+		t.Errorf("Expected rate 0 to drop messages")
+	}
+
+	kept := 0
+	c := &sampleConfig{Rate: 0.25}
+	for i := 0; i < 10000; i++ {
+		if c.keep(msg) {
+			kept++
+		}
+	}
+	if kept < 2000 || kept > 3000 {
+		t.Errorf("Expected about 2500 of 10000 messages kept at rate 0.25, kept %d", kept)
+	}
+}
+
+func TestSampleByMidIsDeterministic(t *testing.T) {
+	c := &sampleConfig{Rate: 0.5, Mode: sampleByMid}
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		msg := &centrifugoBroadcastParams{
+			Channels: []string{"a"},
+			Data:     json.RawMessage(fmt.Sprintf("{\"mid\":\"m%d\"}", i)),
+		}
+		first := c.keep(msg)
+		for j := 0; j < 3; j++ {
+			if c.keep(msg) != first {
+				t.Fatalf("Expected the same decision for mid m%d every time", i)
+			}
+		}
+		if first {
+			kept++
+		}
+	}
+	if kept < 400 || kept > 600 {
+		t.Errorf("Expected about 500 of 1000 messages kept at rate 0.5, kept %d", kept)
+	}
+}
@@ -40,7 +40,44 @@ struct LogEntry
   2:  string message
 }
 
+/**
+ * What happened to one entry given to LogWithResults
+ */
+enum EntryStatus
+{
+  PUBLISHED,
+  DEBOUNCED,
+  PARTIAL,
+  TRY_LATER,
+  INVALID,
+  STALE,
+  UNAUTHORIZED,
+  DUPLICATE,
+  FILTERED,
+  TOO_LARGE,
+  RATE_LIMITED
+}
+
+/**
+ * Result for one entry. An entry can hold several messages, which are counted
+ * as published (including held for debouncing) or dropped. reason is why the
+ * last dropped one was dropped, as in the dropped.* metrics.
+ */
+struct EntryResult
+{
+  1:  EntryStatus status,
+  2:  i32 published,
+  3:  i32 dropped,
+  4:  string reason
+}
+
 service scribe
 {
   ResultCode Log(1: list<LogEntry> messages);
+
+  /**
+   * Like Log but returns a result for each entry, in the same order.
+   * Entries with status TRY_LATER weren't published and should be retried.
+   */
+  list<EntryResult> LogWithResults(1: list<LogEntry> messages);
 }
\ No newline at end of file
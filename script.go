@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	lua "github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/yuin/gopher-lua"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/yuin/gopher-lua/parse"
+)
+
+// scriptFunc is the global function a script must define. It is called with a
+// table of category, channels, data, ts and ttl for each message and may return:
+//
+//   - nil or false to drop the message
+//   - a message table (possibly the one it was given, modified) to publish it
+//   - a list of message tables to publish several messages instead
+//
+// Only channels and data are read from returned messages.
+const scriptFunc = "process"
+
+const (
+	// Limits on converting between Lua and JSON values
+	scriptMaxDepth    = 64
+	scriptMaxMessages = 1000
+
+	// How many idle Lua states to keep for reuse
+	scriptPoolSize = 64
+	// How often to check the script file for changes
+	scriptReloadInterval = 1 * time.Second
+	// How often the memory watchdog checks running calls
+	scriptMemoryCheckInterval = 10 * time.Millisecond
+)
+
+// Globals removed from scripts since they give access to the filesystem
+var scriptUnsafeGlobals = []string{"dofile", "loadfile", "require", "module"}
+
+var (
+	errScriptTimeout = errors.New("script timed out")
+	errScriptMemory  = errors.New("script went over its memory limit")
+)
+
+// ScriptEngine runs a Lua script against each parsed message. The script file is
+// watched and reloaded when it changes. Lua states are not goroutine safe so a
+// pool of them is kept for concurrent connections.
+type ScriptEngine struct {
+	path      string
+	timeout   time.Duration
+	maxMemory int64
+	opts      lua.Options
+	sd        statsd.Statsd
+	watchdog  *scriptWatchdog
+
+	mu         sync.Mutex
+	proto      *lua.FunctionProto
+	modTime    time.Time
+	generation int
+
+	pool chan *scriptState
+}
+
+// scriptState is a Lua state with the script loaded into it
+type scriptState struct {
+	L          *lua.LState
+	json       *luaJSON
+	generation int
+}
+
+// NewScriptEngine loads the script at path. Each call is limited to timeout, the
+// Lua value stack to maxRegistry slots and the memory it allocates to about
+// maxMemory bytes.
+func NewScriptEngine(path string, timeout time.Duration, maxRegistry int, maxMemory int64, sd statsd.Statsd) (*ScriptEngine, error) {
+	s := &ScriptEngine{
+		path:      path,
+		timeout:   timeout,
+		maxMemory: maxMemory,
+		watchdog:  newScriptWatchdog(uint64(maxMemory)),
+		opts: lua.Options{
+			CallStackSize:   lua.CallStackSize,
+			RegistrySize:    lua.RegistrySize,
+			RegistryMaxSize: maxRegistry,
+			SkipOpenLibs:    true,
+		},
+		sd:   sd,
+		pool: make(chan *scriptState, scriptPoolSize),
+	}
+	if s.opts.RegistryMaxSize < s.opts.RegistrySize {
+		s.opts.RegistrySize = s.opts.RegistryMaxSize
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load compiles the script file if it has changed since it was last loaded
+func (s *ScriptEngine) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	unchanged := s.proto != nil && info.ModTime().Equal(s.modTime)
+	s.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	chunk, err := parse.Parse(f, s.path)
+	if err != nil {
+		return err
+	}
+	proto, err := lua.Compile(chunk, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.proto = proto
+	s.modTime = info.ModTime()
+	s.generation++
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch polls the script file and reloads it when it changes. If the new version
+// fails to compile the previous one keeps running.
+func (s *ScriptEngine) Watch() {
+	go func() {
+		for range time.Tick(scriptReloadInterval) {
+			if err := s.load(); err != nil {
+				glog.Errorf("Failed to reload script %s, keeping previous version. err: %s", s.path, err)
+				s.sd.Incr("error.script_load_fail", 1)
+			}
+		}
+	}()
+}
+
+// getState returns a Lua state running the current version of the script
+func (s *ScriptEngine) getState() (*scriptState, error) {
+	s.mu.Lock()
+	proto, generation := s.proto, s.generation
+	s.mu.Unlock()
+
+	for {
+		var st *scriptState
+		select {
+		case st = <-s.pool:
+		default:
+		}
+		if st == nil {
+			break
+		}
+		if st.generation == generation {
+			return st, nil
+		}
+		// Script was reloaded since this state was created
+		st.L.Close()
+	}
+
+	L := lua.NewState(s.opts)
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range scriptUnsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// string.rep can allocate far more in one step than the watchdog can catch
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(s.strRep))
+	}
+	conv := newLuaJSON(L)
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		L.Close()
+		return nil, err
+	}
+	if _, ok := L.GetGlobal(scriptFunc).(*lua.LFunction); !ok {
+		L.Close()
+		return nil, fmt.Errorf("script %s does not define a %s function", s.path, scriptFunc)
+	}
+	return &scriptState{L: L, json: conv, generation: generation}, nil
+}
+
+// putState returns a state to the pool or closes it if the pool is full
+func (s *ScriptEngine) putState(st *scriptState) {
+	select {
+	case s.pool <- st:
+	default:
+		st.L.Close()
+	}
+}
+
+// Process runs the script on a message from category. It returns the messages
+// the script wants published, which is empty if it dropped the message.
+func (s *ScriptEngine) Process(category string, msg *centrifugoBroadcastParams) ([]*centrifugoBroadcastParams, error) {
+	st, err := s.getState()
+	if err != nil {
+		s.sd.Incr("error.script_fail", 1)
+		return nil, err
+	}
+	L := st.L
+
+	arg, data, err := st.json.messageToLua(category, msg)
+	if err != nil {
+		s.putState(st)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	call := s.watchdog.start(cancel)
+	L.SetContext(ctx)
+	L.Push(L.GetGlobal(scriptFunc))
+	L.Push(arg)
+	err = L.PCall(1, 1, nil)
+	L.RemoveContext()
+	overMemory := s.watchdog.stop(call)
+	if err != nil {
+		// The state may be left in any condition after an error so don't reuse it
+		L.Close()
+		if overMemory {
+			s.sd.Incr("error.script_memory", 1)
+			return nil, errScriptMemory
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			s.sd.Incr("error.script_timeout", 1)
+			return nil, errScriptTimeout
+		}
+		s.sd.Incr("error.script_fail", 1)
+		return nil, err
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+	out, err := st.json.messagesFromLua(ret, &scriptInput{raw: msg.Data, data: data})
+	s.putState(st)
+	if err != nil {
+		s.sd.Incr("error.script_fail", 1)
+		return nil, err
+	}
+	return out, nil
+}
+
+// strRep is string.rep refusing to build strings over the memory limit
+func (s *ScriptEngine) strRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n > 0 && int64(len(str))*int64(n) > s.maxMemory {
+		L.RaiseError("string.rep result is over the script memory limit")
+	}
+	if n < 0 {
+		n = 0
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// scriptWatchdog cancels script calls that allocate more than limit bytes. Go
+// can't measure one goroutine's allocations, so a call's usage is taken to be how
+// much the heap has grown since the watchdog first saw it running. That counts
+// other work allocating at the same time too, so the limit is approximate and
+// calls shorter than scriptMemoryCheckInterval are never checked.
+type scriptWatchdog struct {
+	limit uint64
+
+	mu      sync.Mutex
+	calls   map[*scriptCall]struct{}
+	running bool
+}
+
+// scriptCall is a running script call watched for its memory use
+type scriptCall struct {
+	cancel context.CancelFunc
+	// baseline is the heap size when the watchdog first saw the call, or 0
+	baseline   uint64
+	overMemory bool
+}
+
+func newScriptWatchdog(limit uint64) *scriptWatchdog {
+	return &scriptWatchdog{limit: limit, calls: make(map[*scriptCall]struct{})}
+}
+
+// start watches a call that is stopped with cancel
+func (w *scriptWatchdog) start(cancel context.CancelFunc) *scriptCall {
+	call := &scriptCall{cancel: cancel}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calls[call] = struct{}{}
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+	return call
+}
+
+// stop stops watching a call, returning whether it was cancelled for going over
+// the limit
+func (w *scriptWatchdog) stop(call *scriptCall) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.calls, call)
+	return call.overMemory
+}
+
+// run checks the heap while any calls are running. Reading memory stats stops
+// the world briefly, so it isn't done when no script is running.
+func (w *scriptWatchdog) run() {
+	ticker := time.NewTicker(scriptMemoryCheckInterval)
+	defer ticker.Stop()
+	var stats runtime.MemStats
+	for range ticker.C {
+		w.mu.Lock()
+		if len(w.calls) < 1 {
+			w.running = false
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+
+		runtime.ReadMemStats(&stats)
+
+		w.mu.Lock()
+		for call := range w.calls {
+			switch {
+			case call.baseline == 0:
+				call.baseline = stats.HeapAlloc
+			case stats.HeapAlloc > call.baseline && stats.HeapAlloc-call.baseline > w.limit && !call.overMemory:
+				call.overMemory = true
+				call.cancel()
+			}
+		}
+		w.mu.Unlock()
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+const testScript = `
+function process(msg)
+	if msg.category == "DROP" then
+		return nil
+	end
+	if msg.category == "LOOP" then
+		while true do end
+	end
+	if msg.category == "FAIL" then
+		error("boom")
+	end
+	if msg.category == "SPLIT" then
+		local out = {}
+		for i, user in ipairs(msg.data.users) do
+			table.insert(out, {channels = {"user#" .. user}, data = {count = msg.data.count}})
+		end
+		return out
+	end
+	msg.channels = {msg.channels[1] .. ":" .. msg.data.kind}
+	msg.data.kind = nil
+	msg.data.age = msg.ttl - msg.ts
+	return msg
+end
+`
+
+func newTestScript(t *testing.T, src string) (*ScriptEngine, func()) {
+	return newTestScriptLimits(t, src, 50*time.Millisecond, 64<<20)
+}
+
+func newTestScriptLimits(t *testing.T, src string, timeout time.Duration, maxMemory int64) (*ScriptEngine, func()) {
+	f, err := ioutil.TempFile("", "script_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err := NewScriptEngine(f.Name(), timeout, 65536, maxMemory, &statsd.NoopClient{})
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+	return s, func() { os.Remove(f.Name()) }
+}
+
+func TestScriptProcess(t *testing.T) {
+	type testCase struct {
+		name      string
+		category  string
+		input     *centrifugoBroadcastParams
+		expectOut []*centrifugoBroadcastParams
+		expectErr bool
+	}
+
+	s, cleanup := newTestScript(t, testScript)
+	defer cleanup()
+
+	tests := []testCase{
+		{
+			name:     "Modify channels and data",
+			category: "HUBD",
+			input: &centrifugoBroadcastParams{
+				Channels: []string{"foo"},
+				Data:     json.RawMessage("{\"kind\":\"bar\",\"ts\":10,\"ttl\":15}"),
+			},
+			expectOut: []*centrifugoBroadcastParams{
+				{
+					Channels: []string{"foo:bar"},
+					Data:     json.RawMessage("{\"age\":5,\"ts\":10,\"ttl\":15}"),
+				},
+			},
+		},
+		{
+			name:     "Drop",
+			category: "DROP",
+			input: &centrifugoBroadcastParams{
+				Channels: []string{"foo"},
+				Data:     json.RawMessage("{}"),
+			},
+			expectOut: nil,
+		},
+		{
+			name:     "Return several messages",
+			category: "SPLIT",
+			input: &centrifugoBroadcastParams{
+				Channels: []string{"foo"},
+				Data:     json.RawMessage("{\"users\":[\"bob\",\"alice\"],\"count\":3}"),
+			},
+			expectOut: []*centrifugoBroadcastParams{
+				{
+					Channels: []string{"user#bob"},
+					Data:     json.RawMessage("{\"count\":3}"),
+				},
+				{
+					Channels: []string{"user#alice"},
+					Data:     json.RawMessage("{\"count\":3}"),
+				},
+			},
+		},
+		{
+			name:     "Runtime error",
+			category: "FAIL",
+			input: &centrifugoBroadcastParams{
+				Channels: []string{"foo"},
+				Data:     json.RawMessage("{}"),
+			},
+			expectErr: true,
+		},
+		{
+			name:     "Timeout",
+			category: "LOOP",
+			input: &centrifugoBroadcastParams{
+				Channels: []string{"foo"},
+				Data:     json.RawMessage("{}"),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		out, err := s.Process(test.category, test.input)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Failed case %s: expected error got nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		if len(out) == 0 && len(test.expectOut) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(test.expectOut, out) {
+			t.Errorf("Failed case %s: expected output %+v got %+v", test.name, test.expectOut, out)
+		}
+	}
+}
+
+func TestScriptMissingProcessFunction(t *testing.T) {
+	s, cleanup := newTestScript(t, "x = 1")
+	defer cleanup()
+
+	_, err := s.Process("HUBD", &centrifugoBroadcastParams{
+		Channels: []string{"foo"},
+		Data:     json.RawMessage("{}"),
+	})
+	if err == nil {
+		t.Errorf("Expected error when script has no process function")
+	}
+}
+
+func TestScriptMemoryLimit(t *testing.T) {
+	type testCase struct {
+		name      string
+		src       string
+		expectErr error // nil for any error
+	}
+
+	tests := []testCase{
+		{
+			name:      "Huge string",
+			src:       `function process(msg) local s = string.rep("x", 1e9) return msg end`,
+			expectErr: nil,
+		},
+		{
+			name: "Growing table",
+			src: `function process(msg)
+				local t = {}
+				local i = 0
+				while true do
+					i = i + 1
+					t[i] = {i}
+				end
+			end`,
+			expectErr: errScriptMemory,
+		},
+	}
+
+	for _, test := range tests {
+		// The timeout is long enough that only the memory limit stops the script
+		s, cleanup := newTestScriptLimits(t, test.src, 10*time.Second, 8<<20)
+		start := time.Now()
+		_, err := s.Process("HUBD", &centrifugoBroadcastParams{
+			Channels: []string{"foo"},
+			Data:     json.RawMessage("{}"),
+		})
+		cleanup()
+		if err == nil {
+			t.Errorf("Failed case %s: expected error got nil", test.name)
+			continue
+		}
+		if test.expectErr != nil && err != test.expectErr {
+			t.Errorf("Failed case %s: expected error %v got %v", test.name, test.expectErr, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Failed case %s: took %s to stop", test.name, elapsed)
+		}
+	}
+}
+
+func TestScriptKeepsData(t *testing.T) {
+	type testCase struct {
+		name       string
+		src        string
+		data       string
+		expectData string
+	}
+
+	payload := "{\"list\":[],\"n\":null,\"id\":9007199254740993,\"arr\":[1,null,3],\"f\":1.0}"
+	tests := []testCase{
+		{
+			name:       "Identity is byte for byte",
+			src:        `function process(msg) return msg end`,
+			data:       payload,
+			expectData: payload,
+		},
+		{
+			name:       "Data that isn't an object",
+			src:        `function process(msg) return msg end`,
+			data:       "[1, 2]",
+			expectData: "[1, 2]",
+		},
+		{
+			name:       "Unchanged data in a new message",
+			src:        `function process(msg) return {channels = {"other"}, data = msg.data} end`,
+			data:       payload,
+			expectData: payload,
+		},
+		{
+			name: "Changed data keeps nulls, arrays and large ids",
+			src: `function process(msg)
+				msg.data.extra = "id" .. msg.data.id
+				msg.data.arr[2] = 2
+				return msg
+			end`,
+			data:       payload,
+			expectData: "{\"arr\":[1,2,3],\"extra\":\"id9007199254740993\",\"f\":1,\"id\":9007199254740993,\"list\":[],\"n\":null}",
+		},
+		{
+			name: "Nulls can be set and compared",
+			src: `function process(msg)
+				if msg.data.n == json.null then
+					msg.data.list = json.null
+				end
+				return msg
+			end`,
+			data:       "{\"list\":[],\"n\":null}",
+			expectData: "{\"list\":null,\"n\":null}",
+		},
+		{
+			name: "Array holes become nulls",
+			src: `function process(msg)
+				msg.data.arr[2] = nil
+				return msg
+			end`,
+			data:       "{\"arr\":[1,2,3]}",
+			expectData: "{\"arr\":[1,null,3]}",
+		},
+	}
+
+	for _, test := range tests {
+		s, cleanup := newTestScript(t, test.src)
+		out, err := s.Process("HUBD", &centrifugoBroadcastParams{
+			Channels: []string{"foo"},
+			Data:     json.RawMessage(test.data),
+		})
+		cleanup()
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		if len(out) != 1 || string(out[0].Data) != test.expectData {
+			t.Errorf("Failed case %s: expected data %s got %+v", test.name, test.expectData, out)
+		}
+	}
+}
+
+func TestScriptOutputSignature(t *testing.T) {
+	s, cleanup := newTestScript(t, `
+function process(msg)
+	if msg.data.to then
+		msg.channels = {msg.data.to}
+	end
+	return msg
+end
+`)
+	defer cleanup()
+
+	key := []byte("secret")
+	envelope := func(channels []string, data string, signed bool) *scribe.LogEntry {
+		msg := &centrifugoBroadcastParams{Channels: channels, Data: json.RawMessage(data)}
+		if signed {
+			msg.KeyID, msg.Signature = "k", signMessage(key, msg)
+		}
+		b, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &scribe.LogEntry{Category: "HUBD", Message: string(b)}
+	}
+	entries := []*scribe.LogEntry{
+		// Published as the script leaves them where they were sent
+		envelope([]string{"public:1"}, "{}", false),
+		envelope([]string{"admin:1", "public:1"}, "{\"to\":\"admin:1\"}", true),
+		// Routed by the script to channels their signature doesn't cover
+		envelope([]string{"public:1"}, "{\"to\":\"admin:1\"}", false),
+		envelope([]string{"public:1"}, "{\"to\":\"admin:1\"}", true),
+	}
+
+	batch := &batchContext{
+		config:  &Config{Namespaces: map[string]*routeConfig{"admin": {RequireSignature: true}}},
+		keyring: &Keyring{keys: map[string][]byte{"k": key}},
+		script:  s,
+	}
+	sd := newStatsCounters(&statsd.NoopClient{})
+	out, _, err := scribeEntriesToBroadcastCommand(entries, batch, sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 2 {
+		t.Errorf("Expected 2 messages published got %d", len(out.Data))
+	}
+	if got := sd.get("dropped.unsigned"); got != 2 {
+		t.Errorf("Expected 2 messages dropped as unsigned got %d", got)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	lua "github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/yuin/gopher-lua"
+)
+
+// Message data is converted to Lua and back without losing anything the script
+// didn't change, although Lua has no null, can't tell an empty array from an
+// empty object and only has float64 numbers:
+//
+//   - null is json.null, which scripts can compare against and assign
+//   - tables made from JSON arrays stay arrays, even when empty
+//   - integers too large for a Lua number to hold exactly are kept as values
+//     that tostring() and .. turn into their original digits
+//
+// Data a script returns unchanged is published as the bytes it arrived as.
+
+const (
+	// scriptJSONLib is the global table holding json.null
+	scriptJSONLib = "json"
+	// maxExactInteger is the largest integer a float64 holds exactly
+	maxExactInteger = 1 << 53
+)
+
+// jsonNull is the Value of the userdata standing for JSON null
+type jsonNull struct{}
+
+// luaJSON converts message data between JSON and one Lua state's values
+type luaJSON struct {
+	L    *lua.LState
+	null *lua.LUserData
+	// arrayMeta marks tables made from JSON arrays, and numberMeta the userdata
+	// holding integers a Lua number can't
+	arrayMeta  *lua.LTable
+	numberMeta *lua.LTable
+}
+
+func newLuaJSON(L *lua.LState) *luaJSON {
+	c := &luaJSON{L: L, arrayMeta: L.NewTable(), numberMeta: L.NewTable()}
+	c.null = L.NewUserData()
+	c.null.Value = jsonNull{}
+	c.numberMeta.RawSetString("__tostring", L.NewFunction(luaNumberString))
+	c.numberMeta.RawSetString("__concat", L.NewFunction(luaNumberConcat))
+	c.numberMeta.RawSetString("__eq", L.NewFunction(luaNumberEqual))
+
+	lib := L.NewTable()
+	lib.RawSetString("null", c.null)
+	L.SetGlobal(scriptJSONLib, lib)
+	return c
+}
+
+func luaNumberString(L *lua.LState) int {
+	L.Push(lua.LString(fmt.Sprint(L.CheckUserData(1).Value)))
+	return 1
+}
+
+func luaNumberConcat(L *lua.LState) int {
+	L.Push(lua.LString(L.ToStringMeta(L.Get(1)).String() + L.ToStringMeta(L.Get(2)).String()))
+	return 1
+}
+
+func luaNumberEqual(L *lua.LState) int {
+	a, _ := L.CheckUserData(1).Value.(json.Number)
+	b, _ := L.CheckUserData(2).Value.(json.Number)
+	L.Push(lua.LBool(a == b))
+	return 1
+}
+
+// messageToLua builds the table passed to the script for a message, returning
+// it along with the decoded data to compare the script's output with
+func (c *luaJSON) messageToLua(category string, msg *centrifugoBroadcastParams) (*lua.LTable, interface{}, error) {
+	data, err := decodeJSONValue(msg.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ts, ttl lua.LNumber
+	if obj, ok := data.(map[string]interface{}); ok {
+		ts, _ = jsonNumberToLua(obj["ts"])
+		ttl, _ = jsonNumberToLua(obj["ttl"])
+	}
+
+	channels := c.L.NewTable()
+	for _, ch := range msg.Channels {
+		channels.Append(lua.LString(ch))
+	}
+
+	t := c.L.NewTable()
+	t.RawSetString("category", lua.LString(category))
+	t.RawSetString("channels", channels)
+	t.RawSetString("data", c.toLua(data))
+	t.RawSetString("ts", ts)
+	t.RawSetString("ttl", ttl)
+	return t, data, nil
+}
+
+// scriptInput is the data a script was given, so that returned data which is
+// still the same can be published as is
+type scriptInput struct {
+	raw  json.RawMessage
+	data interface{}
+}
+
+// messagesFromLua reads the messages returned by the script
+func (c *luaJSON) messagesFromLua(ret lua.LValue, in *scriptInput) ([]*centrifugoBroadcastParams, error) {
+	if ret == lua.LNil || ret == lua.LFalse {
+		return nil, nil
+	}
+	t, ok := ret.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("script returned %s, expected a table", ret.Type())
+	}
+	if t.RawGetString("channels") != lua.LNil {
+		msg, err := c.messageFromLua(t, in)
+		if err != nil {
+			return nil, err
+		}
+		return []*centrifugoBroadcastParams{msg}, nil
+	}
+
+	n := t.Len()
+	if n > scriptMaxMessages {
+		return nil, fmt.Errorf("script returned %d messages, limit is %d", n, scriptMaxMessages)
+	}
+	out := make([]*centrifugoBroadcastParams, 0, n)
+	for i := 1; i <= n; i++ {
+		mt, ok := t.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("script returned a list containing %s, expected message tables", t.RawGetInt(i).Type())
+		}
+		msg, err := c.messageFromLua(mt, in)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+func (c *luaJSON) messageFromLua(t *lua.LTable, in *scriptInput) (*centrifugoBroadcastParams, error) {
+	var msg centrifugoBroadcastParams
+
+	channels, ok := t.RawGetString("channels").(*lua.LTable)
+	if !ok {
+		return nil, errors.New("script returned a message without a channels list")
+	}
+	for i := 1; i <= channels.Len(); i++ {
+		ch, ok := channels.RawGetInt(i).(lua.LString)
+		if !ok {
+			return nil, errors.New("script returned a non-string channel")
+		}
+		msg.Channels = append(msg.Channels, string(ch))
+	}
+	if len(msg.Channels) < 1 {
+		return nil, errors.New("script returned a message with no channels")
+	}
+
+	value := t.RawGetString("data")
+	if value == lua.LNil {
+		return nil, errors.New("script returned a message without data")
+	}
+	data, err := c.fromLua(value, 0)
+	if err != nil {
+		return nil, err
+	}
+	if sameJSON(data, in.data) {
+		msg.Data = in.raw
+		return &msg, nil
+	}
+	if msg.Data, err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func jsonNumberToLua(v interface{}) (lua.LNumber, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return lua.LNumber(f), true
+}
+
+// exactNumber returns n as a Lua number, or false if it is an integer that a
+// Lua number can't hold exactly
+func exactNumber(n json.Number) (lua.LNumber, bool) {
+	if !strings.ContainsAny(string(n), ".eE") {
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		if err != nil || i > maxExactInteger || i < -maxExactInteger {
+			return 0, false
+		}
+		return lua.LNumber(i), true
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return lua.LNumber(f), true
+}
+
+// toLua converts a decoded JSON value to Lua
+func (c *luaJSON) toLua(v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return c.null
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case json.Number:
+		if n, ok := exactNumber(v); ok {
+			return n
+		}
+		ud := c.L.NewUserData()
+		ud.Value = v
+		ud.Metatable = c.numberMeta
+		return ud
+	case []interface{}:
+		t := c.L.NewTable()
+		t.Metatable = c.arrayMeta
+		for _, item := range v {
+			t.Append(c.toLua(item))
+		}
+		return t
+	case map[string]interface{}:
+		t := c.L.NewTable()
+		for k, item := range v {
+			t.RawSetString(k, c.toLua(item))
+		}
+		return t
+	}
+	return lua.LNil
+}
+
+// fromLua converts a Lua value into something encoding/json can marshal. Tables
+// made from JSON arrays, and others with only consecutive integer keys from 1,
+// become arrays. Other tables, including new empty ones, become objects.
+func (c *luaJSON) fromLua(v lua.LValue, depth int) (interface{}, error) {
+	if depth > scriptMaxDepth {
+		return nil, errors.New("script returned data nested too deeply")
+	}
+	switch v := v.(type) {
+	case *lua.LNilType:
+		// Only found in holes left in arrays
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case *lua.LUserData:
+		switch value := v.Value.(type) {
+		case jsonNull:
+			return nil, nil
+		case json.Number:
+			return value, nil
+		}
+	case *lua.LTable:
+		if n, ok := c.arrayLength(v); ok {
+			arr := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				item, err := c.fromLua(v.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, item)
+			}
+			return arr, nil
+		}
+		obj := make(map[string]interface{})
+		var err error
+		v.ForEach(func(k, item lua.LValue) {
+			if err != nil {
+				return
+			}
+			var converted interface{}
+			converted, err = c.fromLua(item, depth+1)
+			obj[k.String()] = converted
+		})
+		if err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("script returned unsupported %s value in data", v.Type())
+}
+
+// arrayLength returns how many items a table holds if it should become a JSON
+// array. Holes in tables made from JSON arrays become nulls.
+func (c *luaJSON) arrayLength(t *lua.LTable) (int, bool) {
+	isArray := t.Metatable == c.arrayMeta
+	n := 0
+	if !isArray {
+		if n = t.Len(); n < 1 {
+			return 0, false
+		}
+	}
+	keysOK := true
+	t.ForEach(func(k, _ lua.LValue) {
+		kn, ok := k.(lua.LNumber)
+		if !ok || kn < 1 || float64(int(kn)) != float64(kn) || (!isArray && int(kn) > n) {
+			keysOK = false
+			return
+		}
+		if int(kn) > n {
+			n = int(kn)
+		}
+	})
+	return n, keysOK
+}
+
+// sameJSON reports whether data converted back from Lua, a, is the same as the
+// decoded JSON value b that the script was given
+func sameJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		v, ok := b.(bool)
+		return ok && a == v
+	case string:
+		v, ok := b.(string)
+		return ok && a == v
+	case float64:
+		n, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		v, exact := exactNumber(n)
+		return exact && float64(v) == a
+	case json.Number:
+		v, ok := b.(json.Number)
+		return ok && a == v
+	case []interface{}:
+		v, ok := b.([]interface{})
+		if !ok || len(a) != len(v) {
+			return false
+		}
+		for i := range a {
+			if !sameJSON(a[i], v[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		v, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(v) {
+			return false
+		}
+		for k, item := range a {
+			if other, ok := v[k]; !ok || !sameJSON(item, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
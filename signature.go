@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+)
+
+var (
+	errUnsigned     = errors.New("message is not signed")
+	errBadSignature = errors.New("message signature does not match")
+)
+
+// Keyring holds the secrets producers sign messages with, by key id. Keys are
+// rotated by adding the new key, moving producers over to it and then removing
+// the old one, reloading the file with SIGHUP at each step.
+type Keyring struct {
+	keys map[string][]byte
+}
+
+// keyringFile is the JSON keyring file given with -keyring
+type keyringFile struct {
+	// Keys maps key ids to base64 encoded secrets
+	Keys map[string]string `json:"keys"`
+}
+
+// LoadKeyring reads the keyring file at path.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyringFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("Failed to parse keyring %s: %s", path, err)
+	}
+	k := &Keyring{keys: make(map[string][]byte, len(f.Keys))}
+	for id, secret := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(key) < 1 {
+			return nil, fmt.Errorf("Invalid keyring %s: key %q is not non-empty base64", path, id)
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// signMessage returns the hex HMAC-SHA256 signature of msg's channels and data
+// with key. Each channel is followed by a newline, then the data as sent.
+func signMessage(key []byte, msg *centrifugoBroadcastParams) string {
+	mac := hmac.New(sha256.New, key)
+	for _, ch := range msg.Channels {
+		mac.Write([]byte(ch))
+		mac.Write([]byte{'\n'})
+	}
+	mac.Write(msg.Data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks msg's signature if it has one, or returns errUnsigned if it
+// doesn't but required is set. The key id and signature are cleared afterwards
+// so they aren't published. It is safe to call on a nil Keyring which knows no keys.
+func (k *Keyring) verify(msg *centrifugoBroadcastParams, required bool) error {
+	kid, sig := msg.KeyID, msg.Signature
+	msg.KeyID, msg.Signature = "", ""
+	if kid == "" && sig == "" {
+		if required {
+			return errUnsigned
+		}
+		return nil
+	}
+
+	var key []byte
+	if k != nil {
+		key = k.keys[kid]
+	}
+	if key == nil {
+		return fmt.Errorf("message signed with unknown key %q", kid)
+	}
+	if !hmac.Equal([]byte(signMessage(key, msg)), []byte(strings.ToLower(sig))) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// signatureCovers reports whether a signature made for signed channels covers
+// every one of channels
+func signatureCovers(signed, channels []string) bool {
+	if len(signed) < 1 {
+		return false
+	}
+	covered := make(map[string]bool, len(signed))
+	for _, ch := range signed {
+		covered[ch] = true
+	}
+	for _, ch := range channels {
+		if !covered[ch] {
+			return false
+		}
+	}
+	return true
+}
+
+// watchKeyring reloads the keyring file into the handler each time the process
+// receives SIGHUP. If the new file is invalid the old keyring is kept.
+func watchKeyring(path string, h *Handler) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			k, err := LoadKeyring(path)
+			if err != nil {
+				glog.Errorf("Failed to reload keyring, keeping previous one. err: %s", err)
+				continue
+			}
+			h.SetKeyring(k)
+			glog.Infof("Reloaded keyring from %s", path)
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	type testCase struct {
+		name      string
+		kid       string
+		sig       func(msg *centrifugoBroadcastParams) string
+		required  bool
+		expectErr error
+		// Unknown keys have their own error so just check it isn't another one
+		expectUnknown bool
+	}
+
+	f, err := ioutil.TempFile("", "keyring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	// "old-secret" and "new-secret"
+	f.WriteString("{\"keys\": {\"2015-01\": \"b2xkLXNlY3JldA==\", \"2015-02\": \"bmV3LXNlY3JldA==\"}}")
+	f.Close()
+	keyring, err := LoadKeyring(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signWith := func(secret string) func(msg *centrifugoBroadcastParams) string {
+		return func(msg *centrifugoBroadcastParams) string {
+			return signMessage([]byte(secret), msg)
+		}
+	}
+	tests := []testCase{
+		{
+			name: "Unsigned and not required",
+		},
+		{
+			name:      "Unsigned and required",
+			required:  true,
+			expectErr: errUnsigned,
+		},
+		{
+			name:     "Signed with current key",
+			kid:      "2015-02",
+			sig:      signWith("new-secret"),
+			required: true,
+		},
+		{
+			name: "Signed with old key during rotation",
+			kid:  "2015-01",
+			sig:  signWith("old-secret"),
+		},
+		{
+			name: "Upper case hex",
+			kid:  "2015-02",
+			sig: func(msg *centrifugoBroadcastParams) string {
+				return strings.ToUpper(signMessage([]byte("new-secret"), msg))
+			},
+		},
+		{
+			name:      "Signed with wrong secret",
+			kid:       "2015-02",
+			sig:       signWith("old-secret"),
+			expectErr: errBadSignature,
+		},
+		{
+			name:          "Signed with unknown key",
+			kid:           "2014-12",
+			sig:           signWith("old-secret"),
+			expectUnknown: true,
+		},
+		{
+			name: "Channels are signed",
+			kid:  "2015-02",
+			sig: func(msg *centrifugoBroadcastParams) string {
+				return signMessage([]byte("new-secret"), &centrifugoBroadcastParams{
+					Channels: []string{"foo"},
+					Data:     msg.Data,
+				})
+			},
+			expectErr: errBadSignature,
+		},
+	}
+
+	for _, test := range tests {
+		msg := &centrifugoBroadcastParams{
+			Channels: []string{"foo", "bar"},
+			Data:     json.RawMessage("{\"foo\": 1}"),
+			KeyID:    test.kid,
+		}
+		if test.sig != nil {
+			msg.Signature = test.sig(msg)
+		}
+		err := keyring.verify(msg, test.required)
+		switch {
+		case test.expectUnknown:
+			if err == nil || err == errBadSignature || err == errUnsigned {
+				t.Errorf("Failed case %s: expected unknown key error got %v", test.name, err)
+			}
+		case err != test.expectErr:
+			t.Errorf("Failed case %s: expected error %v got %v", test.name, test.expectErr, err)
+		}
+		if msg.KeyID != "" || msg.Signature != "" {
+			t.Errorf("Failed case %s: signature was not cleared", test.name)
+		}
+	}
+}
+
+func TestSignatureRequired(t *testing.T) {
+	cfg := &Config{
+		Categories: map[string]*routeConfig{"secure": {RequireSignature: true}},
+		Namespaces: map[string]*routeConfig{"admin": {RequireSignature: true}},
+	}
+	if !cfg.signatureRequired("secure", []string{"foo"}) {
+		t.Errorf("Expected category to require signature")
+	}
+	if !cfg.signatureRequired("other", []string{"foo", "admin:1"}) {
+		t.Errorf("Expected namespace to require signature")
+	}
+	if cfg.signatureRequired("other", []string{"foo", "public:1"}) {
+		t.Errorf("Expected signature not to be required")
+	}
+	var nilCfg *Config
+	if nilCfg.signatureRequired("secure", []string{"admin:1"}) {
+		t.Errorf("Expected nil config not to require signature")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+)
+
+// Addresses starting with this are paths of Unix domain sockets to listen on
+const unixScheme = "unix://"
+
+// Permissions of Unix domain socket files if not set with -socket-mode
+const defaultSocketMode = "0660"
+
+// unixSocketPath returns the socket path addr names and true, or false if addr
+// is a host:port
+func unixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, unixScheme), true
+}
+
+// parseSocketMode parses octal file permissions like 0660
+func parseSocketMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("Invalid socket mode %q, expected octal permissions like 0660", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// listen listens on a host:port over TCP, or on a Unix domain socket for a
+// unix:// address. The socket file is created with mode permissions, replacing
+// any stale one left behind by a process that didn't shut down cleanly.
+func listen(addr string, mode os.FileMode) (net.Listener, error) {
+	path, ok := unixSocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket file at path if nothing is listening on
+// it. Files that aren't sockets are left alone so a typo can't delete them.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("Can't listen on %s: file exists and isn't a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("Can't listen on %s: another process is listening on it", path)
+	}
+	glog.Infof("Removing stale socket %s", path)
+	return os.Remove(path)
+}
+
+// listenerServerTransport serves Thrift connections accepted from a listener,
+// over TLS if tlsConfig is set. Unlike thrift.TServerSocket the listener can be
+// a Unix domain socket.
+type listenerServerTransport struct {
+	listener      net.Listener
+	tlsConfig     *tls.Config
+	clientTimeout time.Duration
+
+	mu          sync.Mutex
+	interrupted bool
+}
+
+func newListenerServerTransport(l net.Listener, tlsConfig *tls.Config, clientTimeout time.Duration) *listenerServerTransport {
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+	return &listenerServerTransport{listener: l, tlsConfig: tlsConfig, clientTimeout: clientTimeout}
+}
+
+// Listen does nothing as the listener is already listening
+func (t *listenerServerTransport) Listen() error {
+	return nil
+}
+
+func (t *listenerServerTransport) Accept() (thrift.TTransport, error) {
+	t.mu.Lock()
+	interrupted := t.interrupted
+	t.mu.Unlock()
+	if interrupted {
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "Server socket interrupted")
+	}
+
+	conn, err := t.listener.Accept()
+	if err != nil {
+		return nil, thrift.NewTTransportExceptionFromError(err)
+	}
+	if t.tlsConfig != nil {
+		return thrift.NewTSSLSocketFromConnTimeout(conn, t.tlsConfig, t.clientTimeout), nil
+	}
+	return thrift.NewTSocketFromConnTimeout(conn, t.clientTimeout), nil
+}
+
+func (t *listenerServerTransport) Close() error {
+	return t.listener.Close()
+}
+
+func (t *listenerServerTransport) Interrupt() error {
+	t.mu.Lock()
+	t.interrupted = true
+	t.mu.Unlock()
+	return t.Close()
+}
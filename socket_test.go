@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+func TestParseSocketMode(t *testing.T) {
+	type testCase struct {
+		name   string
+		expect os.FileMode
+		ok     bool
+	}
+
+	tests := []testCase{
+		{"0660", 0660, true},
+		{"600", 0600, true},
+		{"0777", 0777, true},
+		{"0999", 0, false},
+		{"01777", 0, false},
+		{"rw-rw----", 0, false},
+	}
+
+	for _, test := range tests {
+		mode, err := parseSocketMode(test.name)
+		if (err == nil) != test.ok || mode != test.expect {
+			t.Errorf("Failed case %s: expected %v (ok %v) got %v, err %v", test.name, test.expect, test.ok, mode, err)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scriber.sock")
+	addr := unixScheme + path
+
+	// Leave a socket file behind as if a previous process was killed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := listen(addr, 0600)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, err %v", err)
+	}
+	defer l.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket mode 0600 got %v", info.Mode().Perm())
+	}
+
+	if _, err := listen(addr, 0600); err == nil {
+		t.Errorf("Expected error listening on a socket in use")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listen(unixScheme+file, 0600); err == nil {
+		t.Errorf("Expected error listening on a regular file")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Expected regular file to be left alone, err %v", err)
+	}
+}
+
+func TestServeUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scriber.sock")
+
+	l, err := listen(unixScheme+path, 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := &connLimits{maxPerIP: 1}
+	limits.deny, _ = parseCIDRs("0.0.0.0/0,::/0")
+	transport := newLimitedServerTransport(newListenerServerTransport(l, nil, 5*time.Second), limits, &statsd.NoopClient{})
+	handler := &countingScribe{received: make(chan string, 1)}
+	server := thrift.NewTSimpleServer4(scribe.NewScribeProcessor(handler), transport,
+		thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory()), thrift.NewTBinaryProtocolFactoryDefault())
+	go server.Serve()
+	defer server.Stop()
+
+	// CIDR lists don't apply to local clients
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	socket := thrift.NewTSocketFromConnTimeout(conn, 5*time.Second)
+	client := scribe.NewScribeClientFactory(thrift.NewTFramedTransport(socket), thrift.NewTBinaryProtocolFactoryDefault())
+	result, err := client.Log([]*scribe.LogEntry{{Category: "test", Message: "hello"}})
+	if err != nil || result != scribe.ResultCode_OK {
+		t.Fatalf("Expected OK got %v, err %v", result, err)
+	}
+	if got := <-handler.received; got != "hello" {
+		t.Errorf("Expected hello got %q", got)
+	}
+
+	// But they do share the per IP limit
+	second, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Expected second local connection to be closed")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// statsCounters passes metrics on to statsd and also keeps running totals of
+// counters, so they can be reported over fb303 without a statsd server.
+type statsCounters struct {
+	statsd.Statsd
+
+	// counters maps each counter's name to its *int64 total. Counters are
+	// incremented on every message so they are added to atomically rather than
+	// under a lock.
+	counters sync.Map
+}
+
+func newStatsCounters(sd statsd.Statsd) *statsCounters {
+	return &statsCounters{Statsd: sd}
+}
+
+func (s *statsCounters) Incr(stat string, count int64) error {
+	s.add(stat, count)
+	return s.Statsd.Incr(stat, count)
+}
+
+func (s *statsCounters) Decr(stat string, count int64) error {
+	s.add(stat, -count)
+	return s.Statsd.Decr(stat, count)
+}
+
+func (s *statsCounters) add(stat string, count int64) {
+	total, ok := s.counters.Load(stat)
+	if !ok {
+		total, _ = s.counters.LoadOrStore(stat, new(int64))
+	}
+	atomic.AddInt64(total.(*int64), count)
+}
+
+// get returns the total for a counter, which is 0 if it was never incremented
+func (s *statsCounters) get(stat string) int64 {
+	total, ok := s.counters.Load(stat)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(total.(*int64))
+}
+
+// snapshot returns a copy of every counter's total
+func (s *statsCounters) snapshot() map[string]int64 {
+	counters := make(map[string]int64)
+	s.counters.Range(func(stat, total interface{}) bool {
+		counters[stat.(string)] = atomic.LoadInt64(total.(*int64))
+		return true
+	})
+	return counters
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	"github.com/DeviantArt/centrifugo-scriber/gen-go/publish"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// Category typed publish requests are routed as if none is given
+const defaultThriftPublishCategory = "thrift"
+
+// The only centrifugo API method requests can use
+const methodBroadcast = "broadcast"
+
+// publishStatuses maps the status of a request, as if it were a Scribe entry
+// with one envelope, to its PublishStatus
+var publishStatuses = map[scribe.EntryStatus]publish.PublishStatus{
+	scribe.EntryStatus_PUBLISHED:    publish.PublishStatus_PUBLISHED,
+	scribe.EntryStatus_DEBOUNCED:    publish.PublishStatus_DEBOUNCED,
+	scribe.EntryStatus_STALE:        publish.PublishStatus_STALE,
+	scribe.EntryStatus_UNAUTHORIZED: publish.PublishStatus_UNAUTHORIZED,
+	scribe.EntryStatus_DUPLICATE:    publish.PublishStatus_DUPLICATE,
+	scribe.EntryStatus_FILTERED:     publish.PublishStatus_FILTERED,
+	scribe.EntryStatus_TOO_LARGE:    publish.PublishStatus_TOO_LARGE,
+	scribe.EntryStatus_RATE_LIMITED: publish.PublishStatus_RATE_LIMITED,
+}
+
+// publishRequestMessage converts a typed request to the message a JSON envelope
+// with the same fields would decode to
+func publishRequestMessage(r *publish.PublishRequest) (*centrifugoBroadcastParams, error) {
+	if r.Method != "" && r.Method != methodBroadcast {
+		return nil, fmt.Errorf("unsupported method %q", r.Method)
+	}
+	// Expiry is checked in uint32 seconds as for every other envelope
+	if r.Ts < 0 || r.Ts > math.MaxUint32 || r.Ttl < 0 {
+		return nil, errors.New("ts and ttl must not be negative and ts must fit in 32 bits")
+	}
+	msg := &centrifugoBroadcastParams{Channels: r.Channels, Data: r.Data}
+	if o := r.Options; o != nil {
+		msg.Users = o.Users
+		msg.ChannelTemplate = o.ChannelTemplate
+		msg.KeyID = o.KeyId
+		msg.Signature = hex.EncodeToString(o.Signature)
+	}
+
+	if len(msg.Data) > 0 {
+		if err := validJSONData(msg.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+	ts, ttl := uint32(r.Ts), uint32(r.Ttl)
+	if ts == 0 && ttl == 0 {
+		ts, ttl = dataExpiry(msg.Data)
+	}
+	if err := checkStale(ts, ttl); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func requestCategory(r *publish.PublishRequest) string {
+	if r.Category == "" {
+		return defaultThriftPublishCategory
+	}
+	return r.Category
+}
+
+// publishRequestsToBroadcastCommand is scribeEntriesToBroadcastCommand for typed
+// requests, each of which is one envelope that is already decoded
+func publishRequestsToBroadcastCommand(requests []*publish.PublishRequest, batch *batchContext, sd statsd.Statsd) (*centrifugoRedisRequest, int64) {
+	var req centrifugoRedisRequest
+	req.Data = make([]centrifugoApiCommand, 0, len(requests))
+	batch.entries = make([]entryResult, len(requests))
+
+	var totalBroadcasts int64
+	for i, r := range requests {
+		msg, err := publishRequestMessage(r)
+		d := decodedMessage{msg: msg, raw: r.Data, err: err}
+		totalBroadcasts += appendEnvelope(&req, d, requestCategory(r), &batch.entries[i], batch, sd)
+	}
+	return &req, totalBroadcasts
+}
+
+// requestResults returns the result of each request in a published batch
+func requestResults(batch *batchContext) []*publish.RequestResult {
+	results := make([]*publish.RequestResult, len(batch.entries))
+	for i := range batch.entries {
+		e := &batch.entries[i]
+		status, ok := publishStatuses[e.status(scribe.ResultCode_OK)]
+		if !ok {
+			status = publish.PublishStatus_INVALID
+		}
+		results[i] = &publish.RequestResult{Status: status, Reason: e.reason}
+	}
+	return results
+}
+
+// Publish publishes typed requests through the same processing as Log, without
+// a JSON envelope to parse. If redis fails nothing is published and a
+// PublishError with code TRY_LATER is returned.
+func (h *Handler) Publish(requests []*publish.PublishRequest) ([]*publish.RequestResult, error) {
+	return h.publishConfig(h.currentConfig(), requests)
+}
+
+// publishConfig is Publish with the processing rules in cfg
+func (h *Handler) publishConfig(cfg *Config, requests []*publish.PublishRequest) ([]*publish.RequestResult, error) {
+	if len(requests) < 1 {
+		return []*publish.RequestResult{}, nil
+	}
+	batch := h.newBatch(cfg)
+	req, totalBroadcasts := publishRequestsToBroadcastCommand(requests, batch, h.sd)
+	if err := h.publishBatch(req, totalBroadcasts, batch); err != nil {
+		return nil, &publish.PublishError{
+			Code:    publish.ErrorCode_TRY_LATER,
+			Message: "Failed to publish: " + err.Error(),
+		}
+	}
+	return requestResults(batch), nil
+}
+
+func (c *clientHandler) Publish(requests []*publish.PublishRequest) ([]*publish.RequestResult, error) {
+	cfg := c.currentConfig().forListener(c.listener)
+	if !cfg.hasClientRules() {
+		return c.publishConfig(cfg, requests)
+	}
+
+	results := make([]*publish.RequestResult, len(requests))
+	var allowed []*publish.PublishRequest
+	var positions []int
+	for i, r := range requests {
+		if cfg.clientAllowed(c.subjects, requestCategory(r)) {
+			allowed = append(allowed, r)
+			positions = append(positions, i)
+			continue
+		}
+		glog.Warningf("Client %q is not permitted to publish to category %s, Dropping request", c.subjects, requestCategory(r))
+		c.sd.Incr("dropped.client_not_allowed", 1)
+		results[i] = &publish.RequestResult{Status: publish.PublishStatus_UNAUTHORIZED, Reason: "client_not_allowed"}
+	}
+
+	published, err := c.publishConfig(cfg, allowed)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range published {
+		results[positions[i]] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	"github.com/DeviantArt/centrifugo-scriber/gen-go/publish"
+)
+
+func TestPublishRequestMessage(t *testing.T) {
+	type testCase struct {
+		name           string
+		request        *publish.PublishRequest
+		expectErr      bool
+		expectStale    bool
+		expectChannels []string
+	}
+
+	data := []byte("{\"foo\":1}")
+	old := time.Now().Add(-time.Minute).Unix()
+	tests := []testCase{
+		{"broadcast", &publish.PublishRequest{Channels: []string{"a", "b"}, Data: data}, false, false, []string{"a", "b"}},
+		{"explicit method", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Method: "broadcast"}, false, false, []string{"a"}},
+		{"other method", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Method: "presence"}, true, false, nil},
+		{"no channels", &publish.PublishRequest{Data: data}, true, false, nil},
+		{"no data", &publish.PublishRequest{Channels: []string{"a"}}, true, false, nil},
+		{"data not an object", &publish.PublishRequest{Channels: []string{"a"}, Data: []byte("[1]")}, false, false, []string{"a"}},
+		{"data not JSON", &publish.PublishRequest{Channels: []string{"a"}, Data: []byte("{")}, true, false, nil},
+		{"negative ttl", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Ttl: -1}, true, false, nil},
+		{"negative ts", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Ts: -1, Ttl: 10}, true, false, nil},
+		{"ts over 32 bits", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Ts: math.MaxUint32 + 1, Ttl: 10}, true, false, nil},
+		{"fresh", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Ts: old, Ttl: 120}, false, false, []string{"a"}},
+		{"stale", &publish.PublishRequest{Channels: []string{"a"}, Data: data, Ts: old, Ttl: 10}, true, true, nil},
+		{"stale by data", &publish.PublishRequest{Channels: []string{"a"}, Data: []byte(fmt.Sprintf("{\"ts\":%d,\"ttl\":10}", old))}, true, true, nil},
+		{
+			"users",
+			&publish.PublishRequest{Data: data, Options: &publish.PublishOptions{Users: []string{"bob"}, ChannelTemplate: "n#{user}"}},
+			false, false, []string{"n#bob"},
+		},
+		{
+			"users without placeholder",
+			&publish.PublishRequest{Data: data, Options: &publish.PublishOptions{Users: []string{"bob"}, ChannelTemplate: "n"}},
+			true, false, nil,
+		},
+	}
+
+	for _, test := range tests {
+		msg, err := publishRequestMessage(test.request)
+		if (err != nil) != test.expectErr {
+			t.Errorf("%s: expected error %v got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if _, stale := err.(*MessageStaleErr); stale != test.expectStale {
+			t.Errorf("%s: expected stale %v got %v", test.name, test.expectStale, err)
+		}
+		if err != nil {
+			continue
+		}
+		msg.expandUserChannels()
+		if len(msg.Channels) != len(test.expectChannels) {
+			t.Errorf("%s: expected channels %v got %v", test.name, test.expectChannels, msg.Channels)
+			continue
+		}
+		for i, ch := range test.expectChannels {
+			if msg.Channels[i] != ch {
+				t.Errorf("%s: expected channels %v got %v", test.name, test.expectChannels, msg.Channels)
+			}
+		}
+	}
+}
+
+func TestPublishSignedRequest(t *testing.T) {
+	k := &Keyring{keys: map[string][]byte{"k1": []byte("secret")}}
+	msg := &centrifugoBroadcastParams{Channels: []string{"a"}, Data: []byte("{\"foo\":1}")}
+	sig := signMessage(k.keys["k1"], msg)
+
+	raw, err := hex.DecodeString(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &publish.PublishRequest{
+		Channels: msg.Channels,
+		Data:     msg.Data,
+		Options:  &publish.PublishOptions{KeyId: "k1", Signature: raw},
+	}
+	decoded, err := publishRequestMessage(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.verify(decoded, true); err != nil {
+		t.Errorf("Expected signature to verify, err: %s", err)
+	}
+}
+
+func TestPublishRequestResults(t *testing.T) {
+	data := []byte("{\"mid\":\"a\"}")
+	requests := []*publish.PublishRequest{
+		{Channels: []string{"a"}, Data: data},
+		{Channels: []string{"a"}, Data: data},
+		{Channels: []string{"b"}, Data: []byte("not json")},
+		{Channels: []string{"c"}, Data: []byte("{}"), Ts: time.Now().Add(-time.Minute).Unix(), Ttl: 1},
+	}
+	expect := []publish.RequestResult{
+		{Status: publish.PublishStatus_PUBLISHED},
+		{Status: publish.PublishStatus_DUPLICATE, Reason: "duplicate"},
+		{Status: publish.PublishStatus_INVALID, Reason: "invalid_format"},
+		{Status: publish.PublishStatus_STALE, Reason: "stale_ttl"},
+	}
+
+	batch := &batchContext{dedup: newLocalDedupStore(time.Minute, 100)}
+	out, broadcasts := publishRequestsToBroadcastCommand(requests, batch, &statsd.NoopClient{})
+	if len(out.Data) != 1 || broadcasts != 1 {
+		t.Errorf("Expected 1 broadcast got %d commands, %d broadcasts", len(out.Data), broadcasts)
+	}
+	results := requestResults(batch)
+	if len(results) != len(expect) {
+		t.Fatalf("Expected %d results got %d", len(expect), len(results))
+	}
+	for i, result := range results {
+		if *result != expect[i] {
+			t.Errorf("Request %d: expected %+v got %+v", i, expect[i], *result)
+		}
+	}
+}
+
+// failingPublisher is a Scribe handler that also implements Publish, failing
+// every call as if redis were down
+type failingPublisher struct {
+	countingScribe
+}
+
+func (p *failingPublisher) Publish(requests []*publish.PublishRequest) ([]*publish.RequestResult, error) {
+	return nil, &publish.PublishError{Code: publish.ErrorCode_TRY_LATER, Message: "redis down"}
+}
+
+func TestPublishServedWithScribe(t *testing.T) {
+	socket, err := thrift.NewTServerSocketTimeout("127.0.0.1:0", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := socket.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	transportFactory := thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())
+	protocolFactory := thrift.NewTBinaryProtocolFactoryDefault()
+	handler := &failingPublisher{countingScribe{received: make(chan string, 1)}}
+	server := thrift.NewTSimpleServer4(newScribeProcessor(handler, nil), socket, transportFactory, protocolFactory)
+	go server.Serve()
+	defer server.Stop()
+
+	conn, err := thrift.NewTSocketTimeout(socket.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := publish.NewPublisherClientFactory(transportFactory.GetTransport(conn), protocolFactory)
+
+	_, err = client.Publish([]*publish.PublishRequest{{Channels: []string{"a"}, Data: []byte("{}")}})
+	perr, ok := err.(*publish.PublishError)
+	if !ok {
+		t.Fatalf("Expected a PublishError got %v", err)
+	}
+	if perr.Code != publish.ErrorCode_TRY_LATER || perr.Message != "redis down" {
+		t.Errorf("Expected TRY_LATER redis down got %+v", perr)
+	}
+}
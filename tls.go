@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// allCategories in a client's list of categories permits every category
+const allCategories = "*"
+
+// How long a client has to complete the TLS handshake
+var tlsHandshakeTimeout = 10 * time.Second
+
+// tlsCerts loads the listener's certificate and the CA bundle client
+// certificates are verified against, and reloads them without dropping
+// connections. New connections use the latest files.
+type tlsCerts struct {
+	certPath, keyPath, caPath string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	cas  *x509.CertPool
+}
+
+// newTLSCerts loads the certificate and key, and the CA bundle if caPath is set,
+// which makes client certificates required.
+func newTLSCerts(certPath, keyPath, caPath string) (*tlsCerts, error) {
+	c := &tlsCerts{certPath: certPath, keyPath: keyPath, caPath: caPath}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *tlsCerts) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("Failed to load TLS certificate: %s", err)
+	}
+	var cas *x509.CertPool
+	if c.caPath != "" {
+		pem, err := ioutil.ReadFile(c.caPath)
+		if err != nil {
+			return err
+		}
+		cas = x509.NewCertPool()
+		if !cas.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("No certificates found in CA bundle %s", c.caPath)
+		}
+	}
+
+	c.mu.Lock()
+	c.cert, c.cas = &cert, cas
+	c.mu.Unlock()
+	return nil
+}
+
+// config returns a server TLS config that picks up reloaded files for each
+// new connection
+func (c *tlsCerts) config() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+			cfg := &tls.Config{Certificates: []tls.Certificate{*c.cert}}
+			if c.cas != nil {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = c.cas
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// watch reloads the files each time the process receives SIGHUP. If any of the
+// new files are invalid the old ones are kept.
+func (c *tlsCerts) watch() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			if err := c.reload(); err != nil {
+				glog.Errorf("Failed to reload TLS certificates, keeping previous ones. err: %s", err)
+				continue
+			}
+			glog.Infof("Reloaded TLS certificate %s", c.certPath)
+		}
+	}()
+}
+
+// clientSubjects returns the names a client certificate can be listed under in
+// the config: its full subject distinguished name and its common name.
+func clientSubjects(cert *x509.Certificate) []string {
+	return []string{cert.Subject.String(), cert.Subject.CommonName}
+}
+
+// clientProcessorFactory makes a processor for each connection which only
+// accepts the categories its client certificate is permitted to log to.
+type clientProcessorFactory struct {
+	handler *Handler
+	// listener is the config of the listener connections are made to when it
+	// was started, or nil for -addr
+	listener *listenerConfig
+	// fb303 serves the fb303 methods, if set
+	fb303 *fb303Handler
+}
+
+func (f *clientProcessorFactory) GetProcessor(trans thrift.TTransport) thrift.TProcessor {
+	client := &clientHandler{Handler: f.handler, listener: f.listener}
+	if socket, ok := unwrapTransport(trans).(*thrift.TSSLSocket); ok {
+		if subjects, err := peerSubjects(socket); err != nil {
+			glog.Warningf("TLS handshake with %s failed. err: %s", socket.Conn().RemoteAddr(), err)
+			f.handler.sd.Incr("error.tls_handshake_fail", 1)
+		} else {
+			client.subjects = subjects
+		}
+	}
+	return newScribeProcessor(client, f.fb303)
+}
+
+// peerSubjects completes the TLS handshake and returns the client certificate's
+// subjects, or nil if the client didn't send one
+func peerSubjects(socket *thrift.TSSLSocket) ([]string, error) {
+	conn, ok := socket.Conn().(*tls.Conn)
+	if !ok {
+		return nil, errors.New("not a TLS connection")
+	}
+	// The handshake runs before the socket sets its own deadlines on reads, so a
+	// client that never sends one would otherwise hold the connection forever
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) < 1 {
+		return nil, nil
+	}
+	return clientSubjects(certs[0]), nil
+}
+
+// clientHandler is the Handler for a single connection. If the config lists
+// client certificates, messages in categories the client isn't permitted to
+// log to are dropped. The listener's rules apply on top of the config.
+// Clients that can't present a certificate, such as those over HTTP or UDP,
+// have no subjects so none of their messages are permitted once clients are
+// listed.
+type clientHandler struct {
+	*Handler
+	listener *listenerConfig
+	subjects []string
+}
+
+func (c *clientHandler) Log(messages []*scribe.LogEntry) (scribe.ResultCode, error) {
+	r, _ := c.logBatch(messages)
+	return r, nil
+}
+
+// logBatch is Handler.logBatch for the client, counting the entries it isn't
+// permitted to log as dropped
+func (c *clientHandler) logBatch(messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext) {
+	cfg := c.currentConfig().forListener(c.listener)
+	allowed := allowedEntries(cfg, c.subjects, messages, c.sd)
+	r, batch := c.logBatchConfig(cfg, allowed)
+	batch.dropped += len(messages) - len(allowed)
+	return r, batch
+}
+
+// allowedEntries returns the messages a client with subjects may log, dropping
+// the rest. If the config doesn't list any clients every message is allowed.
+func allowedEntries(cfg *Config, subjects []string, messages []*scribe.LogEntry, sd statsd.Statsd) []*scribe.LogEntry {
+	if !cfg.hasClientRules() {
+		return messages
+	}
+	allowed := make([]*scribe.LogEntry, 0, len(messages))
+	for _, m := range messages {
+		if cfg.clientAllowed(subjects, m.Category) {
+			allowed = append(allowed, m)
+			continue
+		}
+		glog.Warningf("Client %q is not permitted to log to category %s, Dropping message", subjects, m.Category)
+		sd.Incr("dropped.client_not_allowed", 1)
+	}
+	return allowed
+}
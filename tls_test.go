@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// testCert makes a certificate for cn signed by parent, or self signed if parent is nil
+func testCert(t *testing.T, cn string, parent *tls.Certificate) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"DeviantArt"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{cn},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writeCert(t *testing.T, dir, name string, cert *tls.Certificate) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestTLSClientSubjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriber-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := testCert(t, "Test CA", nil)
+	caPath, _ := writeCert(t, dir, "ca", ca)
+	certPath, keyPath := writeCert(t, dir, "server", testCert(t, "scriber", ca))
+	client := testCert(t, "hub", ca)
+
+	certs, err := newTLSCerts(certPath, keyPath, caPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := certs.cert.Leaf
+	// A renewed certificate is picked up by new connections
+	writeCert(t, dir, "server", testCert(t, "scriber", ca))
+	if err := certs.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	tlsClient := tls.Client(clientConn, &tls.Config{
+		ServerName:   "scriber",
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{*client},
+	})
+	go tlsClient.Handshake()
+
+	socket := thrift.NewTSSLSocketFromConnTimeout(tls.Server(serverConn, certs.config()), nil, time.Second)
+	subjects, err := peerSubjects(socket)
+	if err != nil {
+		t.Fatalf("Unexpected handshake error %v", err)
+	}
+	expect := []string{"CN=hub,O=DeviantArt", "hub"}
+	if !reflect.DeepEqual(expect, subjects) {
+		t.Errorf("Expected subjects %v got %v", expect, subjects)
+	}
+	served := tlsClient.ConnectionState().PeerCertificates[0]
+	if served.Equal(first) {
+		t.Errorf("Expected reloaded server certificate to be served")
+	}
+}
+
+func TestAllowedEntries(t *testing.T) {
+	type testCase struct {
+		name     string
+		config   *Config
+		subjects []string
+		expect   []string
+	}
+
+	clients := &Config{Clients: map[string][]string{
+		"CN=hub,O=DeviantArt": {"hub", "events"},
+		"admin":               {allCategories},
+	}}
+	tests := []testCase{
+		{
+			name:   "No client rules",
+			expect: []string{"hub", "events", "other"},
+		},
+		{
+			name:     "Listed by subject",
+			config:   clients,
+			subjects: []string{"CN=hub,O=DeviantArt", "hub"},
+			expect:   []string{"hub", "events"},
+		},
+		{
+			name:     "Listed by common name with all categories",
+			config:   clients,
+			subjects: []string{"CN=admin,O=Other", "admin"},
+			expect:   []string{"hub", "events", "other"},
+		},
+		{
+			name:     "Not listed",
+			config:   clients,
+			subjects: []string{"CN=intruder", "intruder"},
+		},
+		{
+			name:   "No client certificate",
+			config: clients,
+		},
+	}
+
+	for _, test := range tests {
+		var entries []*scribe.LogEntry
+		for _, category := range []string{"hub", "events", "other"} {
+			entries = append(entries, &scribe.LogEntry{Category: category, Message: "{}"})
+		}
+		var categories []string
+		for _, m := range allowedEntries(test.config, test.subjects, entries, &statsd.NoopClient{}) {
+			categories = append(categories, m.Category)
+		}
+		if !reflect.DeepEqual(test.expect, categories) {
+			t.Errorf("Failed case %s: expected categories %v got %v", test.name, test.expect, categories)
+		}
+	}
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { tlsHandshakeTimeout = timeout }(tlsHandshakeTimeout)
+	tlsHandshakeTimeout = 50 * time.Millisecond
+
+	certs := &tlsCerts{cert: testCert(t, "scriber", nil)}
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	// The client connects but never starts the handshake
+	socket := thrift.NewTSSLSocketFromConnTimeout(tls.Server(serverConn, certs.config()), nil, 0)
+	done := make(chan error, 1)
+	go func() {
+		_, err := peerSubjects(socket)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected handshake to fail")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected handshake to time out")
+	}
+}
+
+func TestClientRulesWithoutCertificate(t *testing.T) {
+	handler := &Handler{sd: &statsd.NoopClient{}}
+	handler.SetConfig(&Config{Clients: map[string][]string{"hub": {allCategories}}})
+	// Clients over HTTP and UDP have no certificate, so aren't permitted any
+	// category once clients are listed
+	publish := &publishHTTPHandler{handler: &clientHandler{Handler: handler}, sd: &statsd.NoopClient{}}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/publish", strings.NewReader("{\"channels\":[\"foo\"], \"data\":{}}"))
+	publish.ServeHTTP(w, r)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d got %d", http.StatusAccepted, w.Code)
+	}
+	var got publishResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if expect := (publishResponse{Dropped: 1}); got != expect {
+		t.Errorf("Expected response %+v got %+v", expect, got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Transform operations supported in config
+const (
+	transformDrop   = "drop"
+	transformRename = "rename"
+	transformSet    = "set"
+	transformWrap   = "wrap"
+)
+
+// transformRule is a single declarative change to a message's data payload.
+// Paths are dot separated keys into the data object, e.g. "data.user.id".
+//
+//   - drop removes the key at Path if present
+//   - rename moves the value at Path to the path given in To if present
+//   - set assigns the JSON Value to Path, creating intermediate objects as needed
+//   - wrap replaces data with a new object holding the old data under the key Path.
+//     If Value is given it must be an object whose keys are copied into the wrapper.
+type transformRule struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	To    string          `json:"to"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (t *transformRule) validate() error {
+	if t.Path == "" {
+		return errors.New("path is required")
+	}
+	switch t.Op {
+	case transformDrop:
+	case transformRename:
+		if t.To == "" {
+			return errors.New("rename requires to")
+		}
+	case transformSet:
+		if len(t.Value) < 1 {
+			return errors.New("set requires value")
+		}
+	case transformWrap:
+		if strings.Contains(t.Path, ".") {
+			return errors.New("wrap path must be a single key")
+		}
+	default:
+		return fmt.Errorf("unknown op %q", t.Op)
+	}
+	if len(t.Value) > 0 {
+		v, err := decodeJSONValue(t.Value)
+		if err != nil {
+			return fmt.Errorf("invalid value: %s", err)
+		}
+		if _, ok := v.(map[string]interface{}); t.Op == transformWrap && !ok {
+			return errors.New("wrap value must be an object")
+		}
+	}
+	return nil
+}
+
+// decodeJSONValue decodes JSON keeping numbers as json.Number so that large
+// integer ids survive being re-encoded.
+func decodeJSONValue(raw []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// applyTransforms runs rules in order against a data payload and returns the new
+// payload. Data must be a JSON object.
+func applyTransforms(data json.RawMessage, rules []*transformRule) (json.RawMessage, error) {
+	if len(rules) < 1 {
+		return data, nil
+	}
+	v, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("data payload is not a JSON object")
+	}
+
+	for _, t := range rules {
+		// Values are decoded fresh for each message so that later rules can't
+		// modify an object shared between messages
+		var value interface{}
+		if len(t.Value) > 0 {
+			if value, err = decodeJSONValue(t.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		switch t.Op {
+		case transformDrop:
+			removePath(obj, t.Path)
+		case transformRename:
+			if val, found := removePath(obj, t.Path); found {
+				if err := setPath(obj, t.To, val); err != nil {
+					return nil, err
+				}
+			}
+		case transformSet:
+			if err := setPath(obj, t.Path, value); err != nil {
+				return nil, err
+			}
+		case transformWrap:
+			wrapped := make(map[string]interface{})
+			if extra, ok := value.(map[string]interface{}); ok {
+				for k, v := range extra {
+					wrapped[k] = v
+				}
+			}
+			wrapped[t.Path] = obj
+			obj = wrapped
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+// removePath deletes the value at a dotted path returning it if it was found
+func removePath(obj map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := obj[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+	last := keys[len(keys)-1]
+	val, found := obj[last]
+	delete(obj, last)
+	return val, found
+}
+
+// setPath assigns a value at a dotted path creating any missing intermediate objects
+func setPath(obj map[string]interface{}, path string, val interface{}) error {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		switch next := obj[k].(type) {
+		case map[string]interface{}:
+			obj = next
+		case nil:
+			created := make(map[string]interface{})
+			obj[k] = created
+			obj = created
+		default:
+			return fmt.Errorf("cannot set %s: %s is not an object", path, k)
+		}
+	}
+	obj[keys[len(keys)-1]] = val
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	type testCase struct {
+		name      string
+		input     string
+		rules     []*transformRule
+		expectOut string
+		expectErr bool
+	}
+
+	tests := []testCase{
+		{
+			name:      "No rules",
+			input:     "{\"foo\": 1}",
+			rules:     nil,
+			expectOut: "{\"foo\": 1}",
+		},
+		{
+			name:  "Drop nested field",
+			input: "{\"ts\":1,\"data\":{\"count\":1,\"internal\":\"x\"}}",
+			rules: []*transformRule{
+				{Op: transformDrop, Path: "data.internal"},
+			},
+			expectOut: "{\"data\":{\"count\":1},\"ts\":1}",
+		},
+		{
+			name:  "Drop missing field is a no-op",
+			input: "{\"foo\":1}",
+			rules: []*transformRule{
+				{Op: transformDrop, Path: "bar.baz"},
+			},
+			expectOut: "{\"foo\":1}",
+		},
+		{
+			name:  "Rename key",
+			input: "{\"data\":{\"uid\":12345678901234567890}}",
+			rules: []*transformRule{
+				{Op: transformRename, Path: "data.uid", To: "data.user_id"},
+			},
+			expectOut: "{\"data\":{\"user_id\":12345678901234567890}}",
+		},
+		{
+			name:  "Set constant creating objects",
+			input: "{\"foo\":1}",
+			rules: []*transformRule{
+				{Op: transformSet, Path: "meta.source", Value: json.RawMessage("\"scriber\"")},
+			},
+			expectOut: "{\"foo\":1,\"meta\":{\"source\":\"scriber\"}}",
+		},
+		{
+			name:  "Set through non-object fails",
+			input: "{\"foo\":1}",
+			rules: []*transformRule{
+				{Op: transformSet, Path: "foo.bar", Value: json.RawMessage("1")},
+			},
+			expectErr: true,
+		},
+		{
+			name:  "Wrap in envelope",
+			input: "{\"foo\":1}",
+			rules: []*transformRule{
+				{Op: transformWrap, Path: "payload", Value: json.RawMessage("{\"version\":2}")},
+			},
+			expectOut: "{\"payload\":{\"foo\":1},\"version\":2}",
+		},
+		{
+			name:  "Data is not an object",
+			input: "[1, 2]",
+			rules: []*transformRule{
+				{Op: transformDrop, Path: "foo"},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		for _, r := range test.rules {
+			if err := r.validate(); err != nil {
+				t.Fatalf("Failed case %s: invalid rule %v", test.name, err)
+			}
+		}
+		out, err := applyTransforms(json.RawMessage(test.input), test.rules)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Failed case %s: expected error got nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed case %s: unexpected error %v", test.name, err)
+			continue
+		}
+		if string(out) != test.expectOut {
+			t.Errorf("Failed case %s: expected output %s got %s", test.name, test.expectOut, out)
+		}
+	}
+}
+
+func TestTransformRuleValidation(t *testing.T) {
+	invalid := []*transformRule{
+		{Op: "explode", Path: "foo"},
+		{Op: transformDrop},
+		{Op: transformRename, Path: "foo"},
+		{Op: transformSet, Path: "foo"},
+		{Op: transformSet, Path: "foo", Value: json.RawMessage("{bad")},
+		{Op: transformWrap, Path: "foo.bar"},
+		{Op: transformWrap, Path: "foo", Value: json.RawMessage("1")},
+	}
+	for i, r := range invalid {
+		if err := r.validate(); err == nil {
+			t.Errorf("Failed case %d: expected %+v to be invalid", i, r)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"compress/zlib"
+	"fmt"
+	"sync"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+)
+
+// Thrift transports and protocols selectable with -transport and -protocol
+const (
+	transportFramed   = "framed"
+	transportBuffered = "buffered"
+	transportZlib     = "zlib"
+
+	protocolBinary  = "binary"
+	protocolCompact = "compact"
+	protocolJSON    = "json"
+
+	// detectAuto sniffs the transport or protocol from each connection's first bytes
+	detectAuto = "auto"
+)
+
+// Size of read and write buffers for unframed transports
+const transportBufferSize = 8192
+
+var protocolFactories = map[string]thrift.TProtocolFactory{
+	protocolBinary:  thrift.NewTBinaryProtocolFactoryDefault(),
+	protocolCompact: thrift.NewTCompactProtocolFactory(),
+	protocolJSON:    thrift.NewTJSONProtocolFactory(),
+}
+
+// protocolByFirstByte names the protocol a message starting with b is in, or ""
+// if it isn't the start of a message in any of them. Strict binary messages
+// start with the high byte of the version, compact with its protocol id and JSON
+// with the opening bracket of the message array.
+func protocolByFirstByte(b byte) string {
+	switch b {
+	case 0x80:
+		return protocolBinary
+	case 0x82:
+		return protocolCompact
+	case '[':
+		return protocolJSON
+	}
+	return ""
+}
+
+// isZlibHeader reports whether b starts with a zlib stream header
+func isZlibHeader(b []byte) bool {
+	return len(b) > 1 && b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+// newServerFactories returns the transport and protocol factories for the
+// -transport and -protocol flags. If either is auto, a connection's first bytes
+// are read to choose it.
+func newServerFactories(transport, protocol string, sd statsd.Statsd) (thrift.TTransportFactory, thrift.TProtocolFactory, error) {
+	switch transport {
+	case transportFramed, transportBuffered, transportZlib, detectAuto:
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q", transport)
+	}
+	if _, ok := protocolFactories[protocol]; !ok && protocol != detectAuto {
+		return nil, nil, fmt.Errorf("unknown protocol %q", protocol)
+	}
+
+	if transport == detectAuto || protocol == detectAuto {
+		f := &autoTransportFactory{
+			transport: transport,
+			protocol:  protocol,
+			sd:        sd,
+			pending:   make(map[thrift.TTransport]*detectedTransport),
+		}
+		return f, &autoProtocolFactory{}, nil
+	}
+
+	var tf thrift.TTransportFactory
+	switch transport {
+	case transportFramed:
+		tf = thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())
+	case transportBuffered:
+		tf = thrift.NewTBufferedTransportFactory(transportBufferSize)
+	case transportZlib:
+		tf = thrift.NewTZlibTransportFactory(zlib.DefaultCompression)
+	}
+	return tf, protocolFactories[protocol], nil
+}
+
+// peekTransport reads through a buffer so the first bytes of a connection can be
+// looked at without consuming them.
+type peekTransport struct {
+	thrift.TTransport
+	r *bufio.Reader
+}
+
+func newPeekTransport(trans thrift.TTransport) *peekTransport {
+	return &peekTransport{TTransport: trans, r: bufio.NewReaderSize(trans, transportBufferSize)}
+}
+
+func (p *peekTransport) Read(buf []byte) (int, error) {
+	return p.r.Read(buf)
+}
+
+// detectedTransport is a connection's transport along with the protocol it uses
+type detectedTransport struct {
+	thrift.TTransport
+	transport, protocol string
+}
+
+// autoTransportFactory wraps each connection in the transport given by its first
+// bytes, or the fixed one if transport isn't auto. The protocol is recorded for
+// autoProtocolFactory in the same way.
+type autoTransportFactory struct {
+	transport, protocol string
+	sd                  statsd.Statsd
+
+	// The server gets an input and an output transport for each connection, so
+	// the detected transport is kept for the second call to return too.
+	mu      sync.Mutex
+	pending map[thrift.TTransport]*detectedTransport
+}
+
+func (f *autoTransportFactory) GetTransport(client thrift.TTransport) thrift.TTransport {
+	f.mu.Lock()
+	if t, ok := f.pending[client]; ok {
+		delete(f.pending, client)
+		f.mu.Unlock()
+		return t
+	}
+	f.mu.Unlock()
+
+	t, err := detectTransport(client, f.transport, f.protocol)
+	if err != nil {
+		// Most likely closed before sending anything; reads will fail the same way
+		glog.V(1).Infof("Failed to detect transport. err: %s", err)
+	} else {
+		f.sd.Incr("connections.detected."+t.transport+"."+t.protocol, 1)
+	}
+
+	f.mu.Lock()
+	f.pending[client] = t
+	f.mu.Unlock()
+	return t
+}
+
+// detectTransport wraps trans in the given transport, or sniffs it if it is
+// auto, and works out the protocol in the same way. If the first bytes can't
+// be read, the fixed transport or protocol is used, or else framed binary.
+func detectTransport(trans thrift.TTransport, transport, protocol string) (*detectedTransport, error) {
+	p := newPeekTransport(trans)
+	t := &detectedTransport{transport: transport, protocol: protocol}
+
+	first, err := p.r.Peek(2)
+	if err != nil && len(first) < 1 {
+		if t.transport == detectAuto {
+			t.transport = transportFramed
+		}
+		if t.protocol == detectAuto {
+			t.protocol = protocolBinary
+		}
+		t.TTransport = wrapTransport(p, t.transport)
+		return t, err
+	}
+
+	if t.transport == detectAuto {
+		switch {
+		case protocolByFirstByte(first[0]) != "":
+			t.transport = transportBuffered
+		case isZlibHeader(first):
+			t.transport = transportZlib
+		default:
+			t.transport = transportFramed
+		}
+	}
+	t.TTransport = wrapTransport(p, t.transport)
+	if t.protocol != detectAuto {
+		return t, nil
+	}
+
+	// The protocol starts after the frame length, or inside the zlib stream
+	var start []byte
+	switch t.transport {
+	case transportFramed:
+		start, err = p.r.Peek(5)
+		if len(start) == 5 {
+			start = start[4:]
+		}
+	case transportZlib:
+		// Decompress through another buffer so nothing is lost
+		inner := newPeekTransport(t.TTransport)
+		t.TTransport = inner
+		start, err = inner.r.Peek(1)
+	default:
+		start = first
+	}
+	t.protocol = protocolBinary
+	if len(start) > 0 {
+		if name := protocolByFirstByte(start[0]); name != "" {
+			t.protocol = name
+		}
+	}
+	return t, err
+}
+
+// wrapTransport wraps trans in the named transport
+func wrapTransport(trans thrift.TTransport, transport string) thrift.TTransport {
+	switch transport {
+	case transportBuffered:
+		return thrift.NewTBufferedTransport(trans, transportBufferSize)
+	case transportZlib:
+		// Only fails for an invalid level
+		z, _ := thrift.NewTZlibTransport(trans, zlib.DefaultCompression)
+		return z
+	}
+	return thrift.NewTFramedTransport(trans)
+}
+
+// autoProtocolFactory uses the protocol autoTransportFactory found for a connection
+type autoProtocolFactory struct{}
+
+func (f *autoProtocolFactory) GetProtocol(trans thrift.TTransport) thrift.TProtocol {
+	name := protocolBinary
+	if t, ok := trans.(*detectedTransport); ok {
+		name = t.protocol
+	}
+	return protocolFactories[name].GetProtocol(trans)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"compress/zlib"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/apache/thrift/lib/go/thrift"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// countingScribe accepts and counts every message
+type countingScribe struct {
+	received chan string
+}
+
+func (s *countingScribe) Log(messages []*scribe.LogEntry) (scribe.ResultCode, error) {
+	for _, m := range messages {
+		s.received <- m.Message
+	}
+	return scribe.ResultCode_OK, nil
+}
+
+func (s *countingScribe) LogWithResults(messages []*scribe.LogEntry) ([]*scribe.EntryResult, error) {
+	results := make([]*scribe.EntryResult, len(messages))
+	for i, m := range messages {
+		s.received <- m.Message
+		results[i] = &scribe.EntryResult{Status: scribe.EntryStatus_PUBLISHED, Published: 1}
+	}
+	return results, nil
+}
+
+func TestAutoDetectTransport(t *testing.T) {
+	type testCase struct {
+		transport string
+		protocol  string
+	}
+
+	socket, err := thrift.NewTServerSocketTimeout("127.0.0.1:0", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := socket.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	transportFactory, protocolFactory, err := newServerFactories(detectAuto, detectAuto, &statsd.NoopClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &countingScribe{received: make(chan string, 1)}
+	server := thrift.NewTSimpleServer4(scribe.NewScribeProcessor(handler), socket, transportFactory, protocolFactory)
+	go server.Serve()
+	defer server.Stop()
+
+	var tests []testCase
+	for _, transport := range []string{transportFramed, transportBuffered, transportZlib} {
+		for _, protocol := range []string{protocolBinary, protocolCompact, protocolJSON} {
+			tests = append(tests, testCase{transport, protocol})
+		}
+	}
+
+	for _, test := range tests {
+		name := test.transport + " " + test.protocol
+		conn, err := thrift.NewTSocketTimeout(socket.Addr().String(), 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.Open(); err != nil {
+			t.Fatal(err)
+		}
+		var trans thrift.TTransport
+		switch test.transport {
+		case transportFramed:
+			trans = thrift.NewTFramedTransport(conn)
+		case transportBuffered:
+			trans = thrift.NewTBufferedTransport(conn, transportBufferSize)
+		case transportZlib:
+			trans, _ = thrift.NewTZlibTransport(conn, zlib.DefaultCompression)
+		}
+		client := scribe.NewScribeClientFactory(trans, protocolFactories[test.protocol])
+
+		// Send twice to check the connection keeps working after detection
+		for i := 0; i < 2; i++ {
+			result, err := client.Log([]*scribe.LogEntry{{Category: "test", Message: name}})
+			if err != nil || result != scribe.ResultCode_OK {
+				t.Errorf("Failed case %s: expected OK got %v, err %v", name, result, err)
+				break
+			}
+			if got := <-handler.received; got != name {
+				t.Errorf("Failed case %s: server received %q", name, got)
+			}
+		}
+		conn.Close()
+	}
+}
+
+func TestServerFactories(t *testing.T) {
+	if _, _, err := newServerFactories("http", protocolBinary, &statsd.NoopClient{}); err == nil {
+		t.Errorf("Expected error for unknown transport")
+	}
+	if _, _, err := newServerFactories(transportFramed, "xml", &statsd.NoopClient{}); err == nil {
+		t.Errorf("Expected error for unknown protocol")
+	}
+	_, pf, err := newServerFactories(transportBuffered, protocolCompact, &statsd.NoopClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pf.(*thrift.TCompactProtocolFactory); !ok {
+		t.Errorf("Expected compact protocol factory got %T", pf)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/golang/glog"
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// Largest possible UDP payload
+const maxDatagramSize = 65535
+
+// How often to check how many datagrams the kernel dropped
+const udpKernelDropsInterval = 10 * time.Second
+
+// Linux's tables of open UDP sockets, whose last column counts the datagrams
+// each dropped because its receive buffer was full
+var procNetUDPFiles = []string{"/proc/net/udp", "/proc/net/udp6"}
+
+// udpListener receives fire-and-forget datagrams, each holding one Scribe
+// message (usually an envelope or NDJSON envelopes), and publishes them in
+// batches. Datagrams are queued between reading and publishing so that a slow
+// redis push doesn't stop the socket being read; if the queue is full they are
+// dropped.
+type udpListener struct {
+	conn      net.PacketConn
+	handler   batchLogger
+	category  string
+	batchSize int
+	batchWait time.Duration
+	queue     chan *scribe.LogEntry
+	sd        statsd.Statsd
+}
+
+func newUDPListener(conn net.PacketConn, handler batchLogger, category string, batchSize int, batchWait time.Duration, queueSize int, sd statsd.Statsd) *udpListener {
+	return &udpListener{
+		conn:      conn,
+		handler:   handler,
+		category:  category,
+		batchSize: batchSize,
+		batchWait: batchWait,
+		queue:     make(chan *scribe.LogEntry, queueSize),
+		sd:        sd,
+	}
+}
+
+// run reads and publishes datagrams in the background until the connection is closed
+func (l *udpListener) run() {
+	go l.read()
+	go l.publish()
+	go l.watchKernelDrops()
+}
+
+// watchKernelDrops counts datagrams the kernel dropped because the socket's
+// receive buffer was full in dropped.udp_kernel. It stops once the socket is
+// closed, or straight away where /proc/net/udp isn't available.
+func (l *udpListener) watchKernelDrops() {
+	addr, ok := l.conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	// The count starts at zero when the socket is opened
+	var last uint64
+	for range time.Tick(udpKernelDropsInterval) {
+		drops, found := udpKernelDrops(addr.Port)
+		if !found {
+			glog.V(1).Infof("Stopped counting kernel UDP drops, port %d not found in %v", addr.Port, procNetUDPFiles)
+			return
+		}
+		if drops > last {
+			l.sd.Incr("dropped.udp_kernel", int64(drops-last))
+		}
+		last = drops
+	}
+}
+
+// udpKernelDrops returns how many datagrams the kernel dropped for sockets bound
+// to port, and whether there are any
+func udpKernelDrops(port int) (uint64, bool) {
+	var total uint64
+	var found bool
+	for _, path := range procNetUDPFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		drops, ok := parseUDPDrops(f, port)
+		f.Close()
+		total += drops
+		found = found || ok
+	}
+	return total, found
+}
+
+// parseUDPDrops sums the drops column of the sockets bound to port in a
+// /proc/net/udp table, reporting whether there were any
+func parseUDPDrops(r io.Reader, port int) (uint64, bool) {
+	var total uint64
+	var found bool
+	scanner := bufio.NewScanner(r)
+	// Skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// local_address is hex ip:port
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		if p, err := strconv.ParseUint(fields[1][i+1:], 16, 16); err != nil || int(p) != port {
+			continue
+		}
+		drops, err := strconv.ParseUint(fields[len(fields)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		total += drops
+		found = true
+	}
+	return total, found
+}
+
+func (l *udpListener) read() {
+	defer close(l.queue)
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			glog.Errorf("Stopped reading UDP datagrams. err: %s", err)
+			return
+		}
+		l.sd.Incr("udp.received", 1)
+		if n < 1 {
+			glog.V(1).Infof("Dropping empty datagram from %s", from)
+			l.sd.Incr("dropped.udp_malformed", 1)
+			continue
+		}
+		select {
+		case l.queue <- &scribe.LogEntry{Category: l.category, Message: string(buf[:n])}:
+		default:
+			l.sd.Incr("dropped.udp_overflow", 1)
+		}
+	}
+}
+
+// publish collects queued datagrams into batches of up to batchSize, waiting at
+// most batchWait after the first one for the batch to fill.
+func (l *udpListener) publish() {
+	for first := range l.queue {
+		batch := []*scribe.LogEntry{first}
+		timeout := time.NewTimer(l.batchWait)
+	collect:
+		for len(batch) < l.batchSize {
+			select {
+			case m, ok := <-l.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, m)
+			case <-timeout.C:
+				break collect
+			}
+		}
+		timeout.Stop()
+		l.publishBatch(batch)
+	}
+}
+
+func (l *udpListener) publishBatch(messages []*scribe.LogEntry) {
+	result, batch := l.handler.logBatch(messages)
+	// The handler counts the envelopes it couldn't decode with those from every
+	// other source, so count the datagrams they were in separately
+	var malformed int64
+	for i := range batch.entries {
+		if batch.entries[i].malformed() {
+			malformed++
+		}
+	}
+	l.sd.Incr("dropped.udp_malformed", malformed)
+	if result == scribe.ResultCode_TRY_LATER {
+		// Senders won't retry so these are lost
+		glog.Warningf("Failed to publish %d UDP datagrams, dropping them", len(messages))
+		l.sd.Incr("dropped.udp_publish_fail", int64(len(messages)))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeviantArt/centrifugo-scriber/Godeps/_workspace/src/github.com/quipo/statsd"
+	scribe "github.com/DeviantArt/centrifugo-scriber/gen-go/scribe"
+)
+
+// batchRecorder parses batches like Handler and passes on their size
+type batchRecorder struct {
+	batches chan int
+}
+
+func (r *batchRecorder) logBatch(messages []*scribe.LogEntry) (scribe.ResultCode, *batchContext) {
+	batch := &batchContext{}
+	scribeEntriesToBroadcastCommand(messages, batch, &statsd.NoopClient{})
+	r.batches <- len(messages)
+	return scribe.ResultCode_OK, batch
+}
+
+func TestUDPListener(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sd := newStatsCounters(&statsd.NoopClient{})
+	recorder := &batchRecorder{batches: make(chan int, 10)}
+	newUDPListener(conn, recorder, "udp", 3, time.Second, 100, sd).run()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	datagrams := []string{
+		"{\"channels\":[\"foo\"], \"data\":{\"foo\": 1}}",
+		"{\"channels\":[\"foo\"], \"data\":{\"foo\": 2}}\n{\"channels\":[\"bar\"], \"data\":{\"bar\": 2}}",
+		"not json",
+		"",
+		"{\"channels\":[\"foo\"], \"data\":{\"foo\": 4}}\nnot json", // partly published so not malformed
+		"{\"channels\":[\"foo\"], \"data\":{\"foo\": 3}}",
+	}
+	for _, d := range datagrams {
+		if _, err := client.Write([]byte(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first batch fills up, the second is published after the wait
+	for i, expect := range []int{3, 2} {
+		select {
+		case size := <-recorder.batches:
+			if size != expect {
+				t.Errorf("Expected batch %d to have %d datagrams got %d", i, expect, size)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for batch %d", i)
+		}
+	}
+	if got := sd.get("udp.received"); got != 6 {
+		t.Errorf("Expected 6 datagrams received got %d", got)
+	}
+	// The empty one and the one that can't be parsed
+	if got := sd.get("dropped.udp_malformed"); got != 2 {
+		t.Errorf("Expected 2 malformed datagrams got %d", got)
+	}
+}
+
+func TestUDPListenerOverflow(t *testing.T) {
+	sd := newStatsCounters(&statsd.NoopClient{})
+	l := newUDPListener(nil, &batchRecorder{}, "udp", 1, time.Second, 1, sd)
+	// Nothing is publishing so only the first fits in the queue
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	l.conn = conn
+	go l.read()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		client.Write([]byte("{}"))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for sd.get("udp.received") < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := sd.get("dropped.udp_overflow"); got != 2 {
+		t.Errorf("Expected 2 datagrams to overflow got %d", got)
+	}
+}
+
+func TestParseUDPDrops(t *testing.T) {
+	table := `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  117: 00000000:0010 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1001 2 0000000000000000 7
+  118: 0100007F:0010 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1002 2 0000000000000000 3
+  119: 00000000:0011 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1003 2 0000000000000000 100
+`
+	type testCase struct {
+		port        int
+		expectDrops uint64
+		expectFound bool
+	}
+
+	tests := []testCase{
+		{16, 10, true},
+		{17, 100, true},
+		{18, 0, false},
+	}
+
+	for _, test := range tests {
+		drops, found := parseUDPDrops(strings.NewReader(table), test.port)
+		if drops != test.expectDrops || found != test.expectFound {
+			t.Errorf("Port %d: expected %d drops found %v got %d, %v", test.port, test.expectDrops, test.expectFound, drops, found)
+		}
+	}
+
+	if _, err := os.Stat(procNetUDPFiles[0]); err != nil {
+		return
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, found := udpKernelDrops(conn.LocalAddr().(*net.UDPAddr).Port); !found {
+		t.Errorf("Expected open socket to be found in %s", procNetUDPFiles[0])
+	}
+}